@@ -2,7 +2,11 @@ module github.com/Cyberax/slog-tidbits
 
 go 1.22
 
-require github.com/stretchr/testify v1.8.4
+require (
+	github.com/klauspost/compress v1.17.9
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/sys v0.20.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
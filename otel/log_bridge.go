@@ -0,0 +1,237 @@
+package otel
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"go.opentelemetry.io/otel/log"
+	"log/slog"
+	"math"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const BridgeScopeName = "github.com/Cyberax/slog-tidbits/otel"
+
+// The semantic convention attribute names
+const (
+	ExceptionMessageKey    = "exception.message"
+	ExceptionStacktraceKey = "exception.stacktrace"
+	CodeFilepathKey        = "code.filepath"
+	CodeLinenoKey          = "code.lineno"
+	CodeFunctionKey        = "code.function"
+)
+
+type LogBridgeOptions struct {
+	Level slog.Leveler
+	// The instrumentation scope, BridgeScopeName by default
+	ScopeName    string
+	ScopeVersion string
+	// AddSource adds the code.filepath, code.lineno and code.function attributes
+	AddSource bool
+}
+
+// LogBridgeHandler emits the records as the OpenTelemetry log records through the LoggerProvider.
+// The groups become the nested map attributes, the trace context is taken from the ctx by the SDK,
+// and the StackValue is converted into the exception.message and exception.stacktrace attributes.
+type LogBridgeHandler struct {
+	opts   LogBridgeOptions
+	logger log.Logger
+	goas   []groupOrAttrs
+}
+
+var _ slog.Handler = &LogBridgeHandler{}
+
+// groupOrAttrs is either a group name or the attributes from WithGroup/WithAttrs
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+func NewLogBridgeHandler(provider log.LoggerProvider, opts *LogBridgeOptions) *LogBridgeHandler {
+	h := &LogBridgeHandler{}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelInfo
+	}
+	if h.opts.ScopeName == "" {
+		h.opts.ScopeName = BridgeScopeName
+	}
+	h.logger = provider.Logger(h.opts.ScopeName, log.WithInstrumentationVersion(h.opts.ScopeVersion))
+	return h
+}
+
+// ConvertSeverity maps the slog level to the OTel severity, slog.LevelInfo is log.SeverityInfo and
+// the levels in between map to the intermediate severities (e.g. slog.LevelInfo+1 is log.SeverityInfo2)
+func ConvertSeverity(lvl slog.Level) log.Severity {
+	sev := int(lvl) - int(slog.LevelInfo) + int(log.SeverityInfo)
+	return log.Severity(min(max(sev, int(log.SeverityTrace1)), int(log.SeverityFatal4)))
+}
+
+func (h *LogBridgeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if level < h.opts.Level.Level() {
+		return false
+	}
+	var probe log.Record
+	probe.SetSeverity(ConvertSeverity(level))
+	return h.logger.Enabled(ctx, probe)
+}
+
+func (h *LogBridgeHandler) Handle(ctx context.Context, record slog.Record) error {
+	var res log.Record
+	res.SetTimestamp(record.Time)
+	res.SetObservedTimestamp(time.Now())
+	res.SetSeverity(ConvertSeverity(record.Level))
+	res.SetSeverityText(record.Level.String())
+	res.SetBody(log.StringValue(record.Message))
+
+	if h.opts.AddSource && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		res.AddAttributes(log.String(CodeFilepathKey, frame.File), log.Int(CodeLinenoKey, frame.Line),
+			log.String(CodeFunctionKey, frame.Function))
+	}
+
+	// The exception attributes are top-level, even if the stack is inside a group
+	var exception []log.KeyValue
+
+	kvs := make([]log.KeyValue, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		kvs = appendKeyValue(kvs, &exception, a)
+		return true
+	})
+
+	// Wrap the attributes into the groups, going from the innermost one
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		if goa.group != "" {
+			if len(kvs) != 0 {
+				kvs = []log.KeyValue{log.Map(goa.group, kvs...)}
+			}
+			continue
+		}
+		var pre []log.KeyValue
+		for _, a := range goa.attrs {
+			pre = appendKeyValue(pre, &exception, a)
+		}
+		kvs = append(pre, kvs...)
+	}
+
+	res.AddAttributes(kvs...)
+	res.AddAttributes(exception...)
+
+	h.logger.Emit(ctx, res)
+	return nil
+}
+
+func (h *LogBridgeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{attrs: attrs})
+}
+
+func (h *LogBridgeHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{group: name})
+}
+
+func (h *LogBridgeHandler) withGroupOrAttrs(goa groupOrAttrs) *LogBridgeHandler {
+	res := *h
+	res.goas = make([]groupOrAttrs, len(h.goas)+1)
+	copy(res.goas, h.goas)
+	res.goas[len(h.goas)] = goa
+	return &res
+}
+
+func appendKeyValue(kvs []log.KeyValue, exception *[]log.KeyValue, a slog.Attr) []log.KeyValue {
+	if sv, ok := a.Value.Any().(*tidbits.StackValue); ok && a.Value.Kind() == slog.KindLogValuer {
+		text, _ := sv.MarshalText()
+		*exception = exceptionAttrs(sv.JSONStack()[0].Msg, string(text))
+		return kvs
+	}
+
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+
+	// The stack has been already resolved into its text view
+	if tm, ok := a.Value.Any().(encoding.TextMarshaler); ok && a.Key == tidbits.StackAttrName {
+		text, err := tm.MarshalText()
+		if err == nil {
+			msg, _, _ := strings.Cut(string(text), "\n")
+			*exception = exceptionAttrs(msg, string(text))
+			return kvs
+		}
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		var group []log.KeyValue
+		for _, ga := range a.Value.Group() {
+			group = appendKeyValue(group, exception, ga)
+		}
+		if len(group) == 0 {
+			return kvs
+		}
+		if a.Key == "" {
+			// Inline the group with the empty key
+			return append(kvs, group...)
+		}
+		return append(kvs, log.Map(a.Key, group...))
+	}
+
+	return append(kvs, log.KeyValue{Key: a.Key, Value: convertValue(a.Value)})
+}
+
+func exceptionAttrs(msg, stack string) []log.KeyValue {
+	res := []log.KeyValue{log.String(ExceptionStacktraceKey, stack)}
+	if msg != "" {
+		res = append(res, log.String(ExceptionMessageKey, msg))
+	}
+	return res
+}
+
+func convertValue(v slog.Value) log.Value {
+	switch v.Kind() {
+	case slog.KindString:
+		return log.StringValue(v.String())
+	case slog.KindInt64:
+		return log.Int64Value(v.Int64())
+	case slog.KindUint64:
+		if v.Uint64() > math.MaxInt64 {
+			return log.StringValue(v.String())
+		}
+		return log.Int64Value(int64(v.Uint64()))
+	case slog.KindFloat64:
+		return log.Float64Value(v.Float64())
+	case slog.KindBool:
+		return log.BoolValue(v.Bool())
+	case slog.KindDuration:
+		return log.Int64Value(v.Duration().Nanoseconds())
+	case slog.KindTime:
+		return log.Int64Value(v.Time().UnixNano())
+	}
+
+	switch av := v.Any().(type) {
+	case error:
+		return log.StringValue(av.Error())
+	case []byte:
+		return log.BytesValue(av)
+	case fmt.Stringer:
+		return log.StringValue(av.String())
+	case encoding.TextMarshaler:
+		text, err := av.MarshalText()
+		if err != nil {
+			return log.StringValue("!ERROR:" + err.Error())
+		}
+		return log.StringValue(string(text))
+	default:
+		return log.StringValue(fmt.Sprintf("%+v", av))
+	}
+}
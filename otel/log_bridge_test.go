@@ -0,0 +1,112 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryExporter keeps the exported records in memory
+type memoryExporter struct {
+	mtx     sync.Mutex
+	records []sdklog.Record
+}
+
+var _ sdklog.Exporter = &memoryExporter{}
+
+func (m *memoryExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, r := range records {
+		m.records = append(m.records, r.Clone())
+	}
+	return nil
+}
+
+func (m *memoryExporter) Shutdown(ctx context.Context) error   { return nil }
+func (m *memoryExporter) ForceFlush(ctx context.Context) error { return nil }
+
+func newTestProvider() (*sdklog.LoggerProvider, *memoryExporter) {
+	exporter := &memoryExporter{}
+	return sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter))), exporter
+}
+
+func recordAttrs(r sdklog.Record) map[string]log.Value {
+	res := map[string]log.Value{}
+	r.WalkAttributes(func(kv log.KeyValue) bool {
+		res[kv.Key] = kv.Value
+		return true
+	})
+	return res
+}
+
+func TestLogBridge(t *testing.T) {
+	provider, exporter := newTestProvider()
+	logger := slog.New(NewLogBridgeHandler(provider, &LogBridgeOptions{Level: slog.LevelDebug, AddSource: true}))
+
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	logger.With(slog.String("component", "db")).WithGroup("req").With(slog.Int("id", 1)).
+		DebugContext(ctx, "hello", slog.Group("query", slog.String("table", "users")),
+			slog.Any("err", errors.New("failed")), slog.Group("empty"))
+
+	assert.Equal(t, 1, len(exporter.records))
+	rec := exporter.records[0]
+	assert.Equal(t, "hello", rec.Body().AsString())
+	assert.Equal(t, log.SeverityDebug, rec.Severity())
+	assert.Equal(t, "DEBUG", rec.SeverityText())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rec.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", rec.SpanID().String())
+	assert.Equal(t, trace.FlagsSampled, rec.TraceFlags())
+	assert.Equal(t, BridgeScopeName, rec.InstrumentationScope().Name)
+
+	attrs := recordAttrs(rec)
+	assert.Equal(t, "db", attrs["component"].AsString())
+	assert.Equal(t, log.MapValue(log.Int("id", 1),
+		log.Map("query", log.String("table", "users")), log.String("err", "failed")), attrs["req"])
+	assert.True(t, strings.HasSuffix(attrs[CodeFilepathKey].AsString(), "otel/log_bridge_test.go"))
+	assert.Equal(t, "github.com/Cyberax/slog-tidbits/otel.TestLogBridge", attrs[CodeFunctionKey].AsString())
+}
+
+func TestLogBridgeException(t *testing.T) {
+	provider, exporter := newTestProvider()
+	logger := slog.New(tidbits.NewSlogConvenience(tidbits.SlogOptions{}, NewLogBridgeHandler(provider, nil)))
+
+	logger.Debug("not logged")
+	logger.Error("failed", tidbits.StackTraceAttrWithOptions(tidbits.StackOptions{SkipTesting: true}, "boom"))
+
+	assert.Equal(t, 1, len(exporter.records))
+	rec := exporter.records[0]
+	assert.Equal(t, log.SeverityError, rec.Severity())
+
+	attrs := recordAttrs(rec)
+	assert.Equal(t, 2, len(attrs))
+	assert.Equal(t, "boom", attrs[ExceptionMessageKey].AsString())
+	assert.Equal(t, "boom\n"+
+		"github.com/Cyberax/slog-tidbits/tidbits/stacks.go:61 StackTraceAttrWithOptions\n"+
+		"github.com/Cyberax/slog-tidbits/otel/log_bridge_test.go:88 TestLogBridgeException\n",
+		attrs[ExceptionStacktraceKey].AsString())
+}
+
+func TestConvertSeverity(t *testing.T) {
+	assert.Equal(t, log.SeverityTrace1, ConvertSeverity(slog.Level(-10)))
+	assert.Equal(t, log.SeverityDebug, ConvertSeverity(slog.LevelDebug))
+	assert.Equal(t, log.SeverityInfo, ConvertSeverity(slog.LevelInfo))
+	assert.Equal(t, log.SeverityInfo2, ConvertSeverity(slog.LevelInfo+1))
+	assert.Equal(t, log.SeverityWarn, ConvertSeverity(slog.LevelWarn))
+	assert.Equal(t, log.SeverityError, ConvertSeverity(slog.LevelError))
+	assert.Equal(t, log.SeverityFatal, ConvertSeverity(slog.LevelError+4))
+	assert.Equal(t, log.SeverityFatal4, ConvertSeverity(slog.LevelError+100))
+}
@@ -0,0 +1,39 @@
+package otel
+
+import (
+	"context"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const LogRecordsMetricName = "log.records"
+
+// MetricLogMetrics counts the records with the OTel counter, with the "log.severity", "logger"
+// and "code.function" attributes
+type MetricLogMetrics struct {
+	counter metric.Int64Counter
+}
+
+var _ tidbits.LogMetrics = &MetricLogMetrics{}
+
+func NewMetricLogMetrics(provider metric.MeterProvider) (*MetricLogMetrics, error) {
+	counter, err := provider.Meter(BridgeScopeName).Int64Counter(LogRecordsMetricName,
+		metric.WithDescription("The number of the log records"), metric.WithUnit("{record}"))
+	if err != nil {
+		return nil, err
+	}
+	return &MetricLogMetrics{counter: counter}, nil
+}
+
+func (o *MetricLogMetrics) CountRecord(ctx context.Context, m tidbits.RecordMetric) {
+	attrs := make([]attribute.KeyValue, 0, 3)
+	attrs = append(attrs, attribute.String(LogSeverityKey, m.Level.String()))
+	if m.Logger != "" {
+		attrs = append(attrs, attribute.String(tidbits.LoggerAttrName, m.Logger))
+	}
+	if m.CallSite != "" {
+		attrs = append(attrs, attribute.String(CodeFunctionKey, m.CallSite))
+	}
+	o.counter.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
@@ -0,0 +1,40 @@
+package otel
+
+import (
+	"context"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"log/slog"
+	"testing"
+)
+
+func TestMetricLogMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	metrics, err := NewMetricLogMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	assert.NoError(t, err)
+
+	logger := slog.New(tidbits.NewMetricsHandler(tidbits.NewCountingNopLogger(slog.LevelInfo).Handler(), metrics))
+	logger.With(slog.String(tidbits.LoggerAttrName, "db")).Error("failed")
+	logger.With(slog.String(tidbits.LoggerAttrName, "db")).Error("failed")
+	logger.Info("info")
+
+	var data metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &data))
+	assert.Equal(t, 1, len(data.ScopeMetrics))
+	assert.Equal(t, LogRecordsMetricName, data.ScopeMetrics[0].Metrics[0].Name)
+
+	counts := map[attribute.Distinct]int64{}
+	sum := data.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	for _, dp := range sum.DataPoints {
+		counts[dp.Attributes.Equivalent()] = dp.Value
+	}
+
+	fn := attribute.String(CodeFunctionKey, "github.com/Cyberax/slog-tidbits/otel.TestMetricLogMetrics")
+	errorSet := attribute.NewSet(attribute.String(LogSeverityKey, "ERROR"),
+		attribute.String(tidbits.LoggerAttrName, "db"), fn)
+	infoSet := attribute.NewSet(attribute.String(LogSeverityKey, "INFO"), fn)
+	assert.Equal(t, map[attribute.Distinct]int64{errorSet.Equivalent(): 2, infoSet.Equivalent(): 1}, counts)
+}
@@ -0,0 +1,220 @@
+package otel
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+const LogSeverityKey = "log.severity"
+
+type SpanEventOptions struct {
+	// Level is the threshold for the span events, slog.LevelWarn by default
+	Level slog.Leveler
+	// ErrorLevel is the level of the records that are recorded with span.RecordError (if they
+	// have a StackValue or an error attribute) and set the span status, slog.LevelError by default
+	ErrorLevel slog.Leveler
+	// AttrAllowlist is the list of the attributes (dotted keys for the groups) that are copied into
+	// the span events, all the attributes are copied if it's nil
+	AttrAllowlist []string
+	// ResolveTimeout is the deadline for each LogValuer, see tidbits.SlogOptions.ResolveTimeout
+	ResolveTimeout time.Duration
+}
+
+// SpanEventHandler attaches the records to the current span as events, and then passes them
+// to the delegate handler. The LogValuers of the events are resolved with tidbits.SafeResolve,
+// before the delegate decides whether to log the record, so the lazy values of the records at
+// or above Level are computed even if the delegate drops them.
+type SpanEventHandler struct {
+	opts     SpanEventOptions
+	delegate slog.Handler
+
+	prefix    string
+	preformed []attribute.KeyValue
+	// Set if the preformed attributes contain the stack or an error
+	exception *spanException
+}
+
+var _ slog.Handler = &SpanEventHandler{}
+
+type spanException struct {
+	err   error
+	stack string
+}
+
+func NewSpanEventHandler(delegate slog.Handler, opts *SpanEventOptions) *SpanEventHandler {
+	h := &SpanEventHandler{delegate: delegate}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelWarn
+	}
+	if h.opts.ErrorLevel == nil {
+		h.opts.ErrorLevel = slog.LevelError
+	}
+	return h
+}
+
+func (h *SpanEventHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level() || h.delegate.Enabled(ctx, level)
+}
+
+func (h *SpanEventHandler) Handle(ctx context.Context, record slog.Record) error {
+	span := trace.SpanFromContext(ctx)
+	if record.Level >= h.opts.Level.Level() && span.IsRecording() {
+		h.addSpanEvent(span, record)
+	}
+
+	if !h.delegate.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.delegate.Handle(ctx, record)
+}
+
+func (h *SpanEventHandler) addSpanEvent(span trace.Span, record slog.Record) {
+	attrs := slices.Clone(h.preformed)
+	exception := h.exception
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = h.appendAttr(attrs, &exception, h.prefix, a)
+		return true
+	})
+	attrs = append(attrs, attribute.String(LogSeverityKey, record.Level.String()))
+
+	opts := []trace.EventOption{trace.WithAttributes(attrs...)}
+	if !record.Time.IsZero() {
+		opts = append(opts, trace.WithTimestamp(record.Time))
+	}
+
+	if record.Level < h.opts.ErrorLevel.Level() || exception == nil {
+		span.AddEvent(record.Message, opts...)
+		return
+	}
+
+	err := exception.err
+	if err == nil {
+		err = errors.New(record.Message)
+	}
+	if exception.stack != "" {
+		opts = append(opts, trace.WithAttributes(attribute.String(ExceptionStacktraceKey, exception.stack)))
+	}
+	span.RecordError(err, opts...)
+	span.SetStatus(codes.Error, record.Message)
+}
+
+func (h *SpanEventHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	res := *h
+	res.delegate = h.delegate.WithAttrs(attrs)
+	res.preformed = slices.Clone(h.preformed)
+	for _, a := range attrs {
+		res.preformed = h.appendAttr(res.preformed, &res.exception, h.prefix, a)
+	}
+	return &res
+}
+
+func (h *SpanEventHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	res := *h
+	res.delegate = h.delegate.WithGroup(name)
+	res.prefix = h.prefix + name + "."
+	return &res
+}
+
+func (h *SpanEventHandler) allowed(key string) bool {
+	return h.opts.AttrAllowlist == nil || slices.Contains(h.opts.AttrAllowlist, key)
+}
+
+// Append the attribute, flattening the groups into the dotted keys
+func (h *SpanEventHandler) appendAttr(res []attribute.KeyValue, exception **spanException,
+	prefix string, a slog.Attr) []attribute.KeyValue {
+
+	if sv, ok := a.Value.Any().(*tidbits.StackValue); ok && a.Value.Kind() == slog.KindLogValuer {
+		text, _ := sv.MarshalText()
+		*exception = mergeException(*exception, nil, string(text))
+		return res
+	}
+
+	a.Value = tidbits.SafeResolve(a.Value, h.opts.ResolveTimeout)
+	if a.Equal(slog.Attr{}) {
+		return res
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			res = h.appendAttr(res, exception, groupPrefix, ga)
+		}
+		return res
+	}
+
+	if err, ok := a.Value.Any().(error); ok {
+		*exception = mergeException(*exception, err, "")
+	}
+
+	key := prefix + a.Key
+	if !h.allowed(key) {
+		return res
+	}
+	return append(res, convertAttribute(key, a.Value))
+}
+
+func mergeException(cur *spanException, err error, stack string) *spanException {
+	res := &spanException{err: err, stack: stack}
+	if cur != nil {
+		if res.err == nil {
+			res.err = cur.err
+		}
+		if res.stack == "" {
+			res.stack = cur.stack
+		}
+	}
+	return res
+}
+
+func convertAttribute(key string, v slog.Value) attribute.KeyValue {
+	switch v.Kind() {
+	case slog.KindString:
+		return attribute.String(key, v.String())
+	case slog.KindInt64:
+		return attribute.Int64(key, v.Int64())
+	case slog.KindFloat64:
+		return attribute.Float64(key, v.Float64())
+	case slog.KindBool:
+		return attribute.Bool(key, v.Bool())
+	case slog.KindTime:
+		return attribute.String(key, v.Time().Format(time.RFC3339Nano))
+	case slog.KindAny:
+		switch av := v.Any().(type) {
+		case error:
+			return attribute.String(key, av.Error())
+		case encoding.TextMarshaler:
+			text, err := av.MarshalText()
+			if err != nil {
+				return attribute.String(key, "!ERROR:"+err.Error())
+			}
+			return attribute.String(key, strings.TrimSuffix(string(text), "\n"))
+		default:
+			return attribute.String(key, fmt.Sprintf("%+v", av))
+		}
+	default:
+		// Uint64 and durations
+		return attribute.String(key, v.String())
+	}
+}
@@ -0,0 +1,100 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSpanEvents(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	sink := tidbits.NewSinkingLogger(slog.LevelDebug)
+	logger := slog.New(NewSpanEventHandler(sink.Logger.Handler(), &SpanEventOptions{
+		AttrAllowlist: []string{"component", "req.id"},
+	}))
+
+	ctx, span := tracer.Start(context.Background(), "op")
+	logger.InfoContext(ctx, "not an event")
+	logger.With(slog.String("component", "db")).WithGroup("req").WarnContext(ctx, "slow query",
+		slog.Int("id", 1), slog.String("filtered", "x"))
+	span.End()
+
+	spans := recorder.Ended()
+	assert.Equal(t, 1, len(spans))
+	events := spans[0].Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "slow query", events[0].Name)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("component", "db"), attribute.Int64("req.id", 1),
+		attribute.String(LogSeverityKey, "WARN")}, events[0].Attributes)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	// All the records are still passed to the delegate
+	assert.Equal(t, 2, strings.Count(sink.Get(), `"msg"`))
+}
+
+func TestSpanEventsRecordError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	// The delegate drops everything, but the span events are still recorded
+	logger := slog.New(NewSpanEventHandler(tidbits.NewNopLogger(slog.Level(100)).Handler(), nil))
+	ctx, span := tracer.Start(context.Background(), "op")
+	logger.ErrorContext(ctx, "plain error")
+	logger.ErrorContext(ctx, "failed", slog.Any("err", errors.New("connection reset")),
+		tidbits.StackTraceAttrWithOptions(tidbits.StackOptions{SkipTesting: true}, "boom"))
+	span.End()
+
+	spans := recorder.Ended()
+	assert.Equal(t, 1, len(spans))
+	events := spans[0].Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "plain error", events[0].Name)
+
+	assert.Equal(t, "exception", events[1].Name)
+	attrs := map[attribute.Key]string{}
+	for _, a := range events[1].Attributes {
+		attrs[a.Key] = a.Value.Emit()
+	}
+	assert.Equal(t, "connection reset", attrs["exception.message"])
+	assert.Equal(t, "connection reset", attrs["err"])
+	assert.Equal(t, "ERROR", attrs[LogSeverityKey])
+	assert.True(t, strings.HasPrefix(attrs[ExceptionStacktraceKey], "boom\n"))
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "failed", spans[0].Status().Description)
+}
+
+type panickingValuer struct{}
+
+func (p panickingValuer) LogValue() slog.Value {
+	panic("boom")
+}
+
+func TestSpanEventsPanickingValuer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	sink := tidbits.NewSinkingLogger(slog.LevelDebug)
+	logger := slog.New(NewSpanEventHandler(sink.Logger.Handler(), nil))
+	ctx, span := tracer.Start(context.Background(), "op")
+	logger.WarnContext(ctx, "bad value", slog.Any("v", panickingValuer{}))
+	span.End()
+
+	events := recorder.Ended()[0].Events()
+	assert.Equal(t, 1, len(events))
+	attrs := map[attribute.Key]string{}
+	for _, a := range events[0].Attributes {
+		attrs[a.Key] = a.Value.Emit()
+	}
+	assert.Equal(t, "!PANIC(boom)", attrs["v.panic"])
+	assert.Contains(t, sink.Get(), `"msg":"bad value"`)
+}
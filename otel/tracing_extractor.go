@@ -3,20 +3,82 @@ package otel
 import (
 	"context"
 	"github.com/Cyberax/slog-tidbits/tidbits"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/trace"
 	"log/slog"
 )
 
+// TracingIdExtractor adds the trace and span IDs from the context. The zero value emits
+// the "trace_id" and "span_id" attributes.
 type TracingIdExtractor struct {
+	// The attribute names, "trace_id" and "span_id" by default
+	TraceIDKey string
+	SpanIDKey  string
+	// Group nests the tracing attributes into the group with this name (e.g. "trace"), the JSON
+	// profiles need the same tidbits.ProfileOptions.TraceGroup to find the IDs
+	Group string
+
+	// AddTraceFlags adds the "trace_flags" attribute with the hex-encoded flags
+	AddTraceFlags bool
+	// AddSampled adds the "sampled" boolean attribute
+	AddSampled bool
+	// AddTraceParent adds the W3C "traceparent" attribute ("00-<trace id>-<span id>-<flags>")
+	AddTraceParent bool
+	// SkipUnsampled doesn't add the tracing attributes for the spans that are not sampled
+	SkipUnsampled bool
+
+	// BaggageMembers are the baggage members that are copied into the attributes with the same names
+	BaggageMembers []string
 }
 
 var _ tidbits.ContextExtractor = &TracingIdExtractor{}
 
 func (o *TracingIdExtractor) MergeContextAttrs(ctx context.Context, curAttrs []slog.Attr) []slog.Attr {
+	curAttrs = o.appendBaggage(ctx, curAttrs)
+
 	span := trace.SpanContextFromContext(ctx)
-	if !span.IsValid() {
+	if !span.IsValid() || (o.SkipUnsampled && !span.IsSampled()) {
 		return curAttrs
 	}
-	return append(curAttrs, slog.String("trace_id", span.TraceID().String()),
-		slog.String("span_id", span.SpanID().String()))
+
+	traceKey, spanKey := o.TraceIDKey, o.SpanIDKey
+	if traceKey == "" {
+		traceKey = "trace_id"
+	}
+	if spanKey == "" {
+		spanKey = "span_id"
+	}
+
+	attrs := []slog.Attr{slog.String(traceKey, span.TraceID().String()),
+		slog.String(spanKey, span.SpanID().String())}
+	if o.AddTraceFlags {
+		attrs = append(attrs, slog.String("trace_flags", span.TraceFlags().String()))
+	}
+	if o.AddSampled {
+		attrs = append(attrs, slog.Bool("sampled", span.IsSampled()))
+	}
+	if o.AddTraceParent {
+		attrs = append(attrs, slog.String("traceparent", "00-"+span.TraceID().String()+"-"+
+			span.SpanID().String()+"-"+span.TraceFlags().String()))
+	}
+
+	if o.Group != "" {
+		return append(curAttrs, slog.Attr{Key: o.Group, Value: slog.GroupValue(attrs...)})
+	}
+	return append(curAttrs, attrs...)
+}
+
+func (o *TracingIdExtractor) appendBaggage(ctx context.Context, curAttrs []slog.Attr) []slog.Attr {
+	if len(o.BaggageMembers) == 0 {
+		return curAttrs
+	}
+	bag := baggage.FromContext(ctx)
+	for _, name := range o.BaggageMembers {
+		member := bag.Member(name)
+		if member.Key() == "" {
+			continue
+		}
+		curAttrs = append(curAttrs, slog.String(name, member.Value()))
+	}
+	return curAttrs
 }
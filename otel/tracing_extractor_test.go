@@ -0,0 +1,46 @@
+package otel
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"testing"
+)
+
+func spanContext(flags trace.TraceFlags) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: flags,
+	}))
+}
+
+func TestTracingIdExtractor(t *testing.T) {
+	ctx := spanContext(trace.FlagsSampled)
+
+	assert.Equal(t, []slog.Attr{slog.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+		slog.String("span_id", "00f067aa0ba902b7")}, (&TracingIdExtractor{}).MergeContextAttrs(ctx, nil))
+	assert.Nil(t, (&TracingIdExtractor{}).MergeContextAttrs(context.Background(), nil))
+
+	full := &TracingIdExtractor{TraceIDKey: "id", SpanIDKey: "span", Group: "trace",
+		AddTraceFlags: true, AddSampled: true, AddTraceParent: true}
+	assert.Equal(t, []slog.Attr{slog.Group("trace", slog.String("id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+		slog.String("span", "00f067aa0ba902b7"), slog.String("trace_flags", "01"), slog.Bool("sampled", true),
+		slog.String("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))},
+		full.MergeContextAttrs(ctx, nil))
+}
+
+func TestTracingIdExtractorUnsampledAndBaggage(t *testing.T) {
+	bag, err := baggage.Parse("tenant=acme,user=42,secret=x")
+	assert.NoError(t, err)
+	ctx := baggage.ContextWithBaggage(spanContext(0), bag)
+
+	extractor := &TracingIdExtractor{SkipUnsampled: true, BaggageMembers: []string{"tenant", "user", "missing"}}
+	assert.Equal(t, []slog.Attr{slog.Int("prev", 1), slog.String("tenant", "acme"), slog.String("user", "42")},
+		extractor.MergeContextAttrs(ctx, []slog.Attr{slog.Int("prev", 1)}))
+
+	extractor.SkipUnsampled = false
+	assert.Equal(t, 4, len(extractor.MergeContextAttrs(ctx, nil)))
+}
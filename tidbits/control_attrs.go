@@ -1,22 +1,39 @@
 package tidbits
 
-import "log/slog"
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+)
 
+// ControlAttr is the attribute that changes the options of the logger derived with it
+// (logger.With(attr)), instead of being logged. Third-party packages can implement their own
+// control attributes.
 type ControlAttr interface {
 	slog.LogValuer
+	// Apply changes the options of the derived logger
+	Apply(opts *SlogOptions)
+}
+
+// CallSiteControlAttr is the control attribute that can also be passed to the logging call
+// (logger.Info("msg", attr)), where it changes the options and the record just for this call.
+// The control attributes that don't implement it are dropped at the call sites with a warning.
+type CallSiteControlAttr interface {
+	ControlAttr
+	ApplyToRecord(opts *SlogOptions, record *slog.Record)
 }
 
 type AttrOrder struct {
 	appendRight bool
 }
 
-var _ ControlAttr = &AttrOrder{}
+var _ CallSiteControlAttr = &AttrOrder{}
 
 type AttrLevel struct {
 	level slog.Level
 }
 
-var _ ControlAttr = &AttrLevel{}
+var _ CallSiteControlAttr = &AttrLevel{}
 
 func (c *AttrOrder) LogValue() slog.Value {
 	if c.appendRight {
@@ -26,6 +43,15 @@ func (c *AttrOrder) LogValue() slog.Value {
 	}
 }
 
+func (c *AttrOrder) Apply(opts *SlogOptions) {
+	opts.AppendNewAttrsRight = c.appendRight
+}
+
+// ApplyToRecord places the record attributes relative to the logger attributes
+func (c *AttrOrder) ApplyToRecord(opts *SlogOptions, record *slog.Record) {
+	c.Apply(opts)
+}
+
 func AddToLeft() slog.Attr {
 	return slog.Any("set_order", &AttrOrder{appendRight: false})
 }
@@ -39,6 +65,138 @@ func (c *AttrLevel) LogValue() slog.Value {
 	return slog.StringValue(c.level.String())
 }
 
+// Apply sets the static level for the logger subtree, replacing the inherited Leveler and
+// the named levels
+func (c *AttrLevel) Apply(opts *SlogOptions) {
+	opts.LogLevel = c.level
+	opts.Leveler = nil
+	opts.levelOverride = true
+}
+
+// ApplyToRecord raises the record level to the attribute level. The record level is never
+// lowered, the record has already passed the level check at its original level.
+func (c *AttrLevel) ApplyToRecord(opts *SlogOptions, record *slog.Record) {
+	record.Level = max(record.Level, c.level)
+}
+
 func WithLogLevel(lvl slog.Level) slog.Attr {
 	return slog.Any("set_level", &AttrLevel{level: lvl})
 }
+
+// AttrName sets the logger name, it's also rendered as the "logger" attribute
+type AttrName struct {
+	name string
+}
+
+var _ ControlAttr = &AttrName{}
+
+func (c *AttrName) LogValue() slog.Value {
+	return slog.StringValue(c.name)
+}
+
+func (c *AttrName) Apply(opts *SlogOptions) {
+	opts.LoggerName = c.name
+}
+
+// WithLoggerName names the logger, the levels for the named loggers are set by SlogOptions.NamedLevels
+func WithLoggerName(name string) slog.Attr {
+	return slog.Any(LoggerAttrName, &AttrName{name: name})
+}
+
+// AttrExtractors adds the context extractors
+type AttrExtractors struct {
+	extractors []ContextExtractor
+}
+
+var _ CallSiteControlAttr = &AttrExtractors{}
+
+func (c *AttrExtractors) LogValue() slog.Value {
+	return slog.IntValue(len(c.extractors))
+}
+
+func (c *AttrExtractors) Apply(opts *SlogOptions) {
+	opts.Extractors = append(slices.Clip(opts.Extractors), c.extractors...)
+}
+
+func (c *AttrExtractors) ApplyToRecord(opts *SlogOptions, record *slog.Record) {
+	c.Apply(opts)
+}
+
+func WithExtractors(extractors ...ContextExtractor) slog.Attr {
+	return slog.Any("set_extractors", &AttrExtractors{extractors: extractors})
+}
+
+// AttrSampling sets the sampling policy, the nil sampler disables the sampling
+type AttrSampling struct {
+	sampler Sampler
+}
+
+var _ CallSiteControlAttr = &AttrSampling{}
+
+func (c *AttrSampling) LogValue() slog.Value {
+	if c.sampler == nil {
+		return slog.StringValue("none")
+	}
+	return slog.StringValue(fmt.Sprintf("%T", c.sampler))
+}
+
+func (c *AttrSampling) Apply(opts *SlogOptions) {
+	opts.Sampler = c.sampler
+}
+
+func (c *AttrSampling) ApplyToRecord(opts *SlogOptions, record *slog.Record) {
+	c.Apply(opts)
+}
+
+func WithSampling(sampler Sampler) slog.Attr {
+	return slog.Any("set_sampling", &AttrSampling{sampler: sampler})
+}
+
+// AttrRedaction adds the redaction rules
+type AttrRedaction struct {
+	rules []RedactionRule
+}
+
+var _ CallSiteControlAttr = &AttrRedaction{}
+
+func (c *AttrRedaction) LogValue() slog.Value {
+	return slog.IntValue(len(c.rules))
+}
+
+func (c *AttrRedaction) Apply(opts *SlogOptions) {
+	opts.Redactions = append(slices.Clip(opts.Redactions), c.rules...)
+}
+
+func (c *AttrRedaction) ApplyToRecord(opts *SlogOptions, record *slog.Record) {
+	c.Apply(opts)
+}
+
+func WithRedaction(rules ...RedactionRule) slog.Attr {
+	return slog.Any("set_redaction", &AttrRedaction{rules: rules})
+}
+
+// AttrPinpoint replaces the pinpoint levels for the logger subtree
+type AttrPinpoint struct {
+	pinpointer *PinpointLogLevels
+}
+
+var _ CallSiteControlAttr = &AttrPinpoint{}
+
+func (c *AttrPinpoint) LogValue() slog.Value {
+	if c.pinpointer == nil {
+		return slog.StringValue("none")
+	}
+	return slog.IntValue(c.pinpointer.numOverrides())
+}
+
+func (c *AttrPinpoint) Apply(opts *SlogOptions) {
+	opts.Pinpointer = c.pinpointer
+}
+
+func (c *AttrPinpoint) ApplyToRecord(opts *SlogOptions, record *slog.Record) {
+	c.Apply(opts)
+}
+
+func WithPinpoint(pinpointer *PinpointLogLevels) slog.Attr {
+	return slog.Any("set_pinpoint", &AttrPinpoint{pinpointer: pinpointer})
+}
@@ -1,8 +1,10 @@
 package tidbits
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
 	"log/slog"
+	"regexp"
 	"testing"
 )
 
@@ -20,3 +22,166 @@ func TestControlsAttrsRendering(t *testing.T) {
 {"time":"","level":"INFO","msg":"Hello","set_order":"right"}
 {"time":"","level":"INFO","msg":"Hello","set_level":"ERROR"}`, sl.Get())
 }
+
+// A third-party control attribute
+type prefixExtractorsReset struct{}
+
+func (p *prefixExtractorsReset) LogValue() slog.Value { return slog.StringValue("reset") }
+func (p *prefixExtractorsReset) Apply(opts *SlogOptions) {
+	opts.Extractors = nil
+}
+
+type constExtractor struct {
+	key, val string
+}
+
+func (c *constExtractor) MergeContextAttrs(ctx context.Context, curAttrs []slog.Attr) []slog.Attr {
+	return append(curAttrs, slog.String(c.key, c.val))
+}
+
+func TestExtractorControls(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	root := slog.New(NewSlogConvenience(SlogOptions{
+		Extractors: []ContextExtractor{&constExtractor{"root", "1"}},
+	}, sink.Handler()))
+
+	child := root.With(WithExtractors(&constExtractor{"child", "2"}))
+	child.Info("child")
+	root.Info("root")
+	child.With(slog.Any("reset", &prefixExtractorsReset{})).Info("reset")
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"child","root":"1","child":"2"}
+{"time":"","level":"INFO","msg":"root","root":"1"}
+{"time":"","level":"INFO","msg":"reset"}`, sink.Get())
+}
+
+func TestSamplingControl(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{}, sink.Handler())).
+		With(WithSampling(NewEveryNthSampler(3, slog.LevelInfo)))
+
+	for i := 0; i < 6; i++ {
+		logger.Info("sampled", slog.Int("i", i))
+	}
+	logger.Warn("always")
+	logger.With(WithSampling(nil)).Info("unsampled")
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"sampled","i":0}
+{"time":"","level":"INFO","msg":"sampled","i":3}
+{"time":"","level":"WARN","msg":"always"}
+{"time":"","level":"INFO","msg":"unsampled"}`, sink.Get())
+}
+
+func TestRedactionControl(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{}, sink.Handler())).
+		With(WithRedaction(RedactKeys("Password")), slog.String("password", "hunter2"))
+	logger = logger.With(WithRedaction(RedactPattern(regexp.MustCompile(`\d{4}-\d{4}`))))
+
+	logger.Info("login", slog.Group("user", slog.String("name", "bob"), slog.String("PASSWORD", "x")),
+		slog.String("card", "card 1234-5678"))
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"login","user":{"name":"bob","PASSWORD":"[REDACTED]"},`+
+		`"card":"card [REDACTED]","password":"[REDACTED]"}`, sink.Get())
+}
+
+func TestRedactionWithGroups(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	root := slog.New(NewSlogConvenience(SlogOptions{Redactions: []RedactionRule{RedactKeys("password")}},
+		sink.Handler()))
+
+	root.With("password", "hunter2").WithGroup("user").Info("login", slog.String("name", "bob"))
+	root.Info("explicit", slog.String("user.password", "hunter2"))
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"login","name":"bob","user.password":"[REDACTED]"}
+{"time":"","level":"INFO","msg":"explicit","user.password":"[REDACTED]"}`, sink.Get())
+}
+
+func TestPinpointControl(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{}, sink.Handler()))
+
+	pinpoint := NewPinpointLogLevels().WithOverride(slog.LevelError, "github.com/Cyberax/slog-tidbits/tidbits")
+	logger.With(WithPinpoint(pinpoint)).Warn("suppressed")
+	logger.Warn("logged")
+
+	assert.Equal(t, `{"time":"","level":"WARN","msg":"logged"}`, sink.Get())
+}
+
+func TestCallSiteControls(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{}, sink.Handler())).With("a", 1)
+
+	logger.Info("right", slog.Int("b", 2), AddToRight())
+	logger.Info("bumped", WithLogLevel(slog.LevelError))
+	logger.Warn("not lowered", WithLogLevel(slog.LevelDebug))
+	logger.Info("redacted", WithRedaction(RedactKeys("b")), slog.Int("b", 2))
+	logger.Info("left as is", slog.Int("b", 2))
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"right","a":1,"b":2}
+{"time":"","level":"ERROR","msg":"bumped","a":1}
+{"time":"","level":"WARN","msg":"not lowered","a":1}
+{"time":"","level":"INFO","msg":"redacted","b":"[REDACTED]","a":1}
+{"time":"","level":"INFO","msg":"left as is","b":2,"a":1}`, sink.Get())
+}
+
+func TestCallSiteControlsStripped(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{}, sink.Handler()))
+
+	for i := 0; i < 2; i++ {
+		logger.Info("named", WithLoggerName("db"))
+	}
+
+	assert.Equal(t, `{"time":"","level":"WARN","msg":"The control attribute can't be applied to a single record, it's ignored","control_attr":"logger"}
+{"time":"","level":"INFO","msg":"named"}
+{"time":"","level":"INFO","msg":"named"}`, sink.Get())
+}
+
+func TestCallSiteControlsInGroups(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{}, sink.Handler()))
+
+	logger.Info("bumped", slog.Group("g", slog.Int("a", 1),
+		slog.Group("nested", WithLogLevel(slog.LevelError), slog.Any("list", []int{1}))))
+	logger.Info("named", slog.Group("g", WithLoggerName("db")))
+
+	assert.Equal(t, `{"time":"","level":"ERROR","msg":"bumped","g":{"a":1,"nested":{"list":[1]}}}
+{"time":"","level":"WARN","msg":"The control attribute can't be applied to a single record, it's ignored","control_attr":"logger"}
+{"time":"","level":"INFO","msg":"named"}`, sink.Get())
+}
+
+func TestCallSiteControlWarningDisabled(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{LogLevel: slog.LevelError}, sink.Handler()))
+	logger.Error("named", WithLoggerName("db"))
+
+	// The warnings are sampled like the other records, the warning takes the second sampler slot
+	sampled := slog.New(NewSlogConvenience(SlogOptions{
+		Sampler: NewEveryNthSampler(2, slog.LevelWarn),
+	}, sink.Handler()))
+	sampled.Info("first")
+	sampled.Info("named", WithLoggerName("db"))
+
+	assert.Equal(t, `{"time":"","level":"ERROR","msg":"named"}
+{"time":"","level":"INFO","msg":"first"}
+{"time":"","level":"INFO","msg":"named"}`, sink.Get())
+}
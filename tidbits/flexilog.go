@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"runtime"
 	"slices"
+	"sync"
+	"time"
 )
 
 type ContextExtractor interface {
@@ -17,6 +19,42 @@ type SlogOptions struct {
 	Pinpointer *PinpointLogLevels
 	LogLevel   slog.Level
 	Extractors []ContextExtractor
+
+	// Leveler replaces LogLevel if set, it's consulted on each call, so the level can be changed for
+	// the whole logger tree (e.g. with *slog.LevelVar)
+	Leveler slog.Leveler
+	// LevelSource decides the level dynamically, it takes precedence over the named and the
+	// configured levels unless it returns false. The WithLogLevel control attribute overrides it
+	// for the logger subtree.
+	LevelSource LevelSource
+
+	// Sampler drops the records that are not sampled, after the level and pinpoint checks
+	Sampler Sampler
+	// Redactions are applied to all the record attributes, including the extracted ones
+	Redactions []RedactionRule
+	// Limits restricts the size of the attribute values and the records
+	Limits *SizeLimits
+	// ResolveTimeout is the deadline for each LogValuer, the slow values are replaced by the
+	// "!TIMEOUT(...)" strings. Zero means no deadline. Each LogValuer runs in its own goroutine,
+	// and the goroutine of a timed out LogValuer keeps running until it returns. The goroutines
+	// of LogValuers that never return are leaked, once there are MaxPendingResolves of them, the
+	// new LogValuers wait for one of them to return and are timed out if it doesn't.
+	ResolveTimeout time.Duration
+
+	// NamedLevels overrides LogLevel and Leveler for the named loggers
+	NamedLevels *NamedLogLevels
+	// LoggerName is the name of the logger, it's set by the WithLoggerName control attribute
+	LoggerName string
+	// The level is set explicitly for the logger subtree with the WithLogLevel control attribute
+	levelOverride bool
+
+	// EmitStackID adds the stack_id attribute with the stack fingerprint to the records with stacks
+	EmitStackID bool
+	// StackIDWithLines makes the stack fingerprint sensitive to the line numbers
+	StackIDWithLines bool
+	// StackDedup enables the deduplication of stack traces, only the first record with the given
+	// stack fingerprint within the window gets the full stack, the rest get just the stack_id
+	StackDedup *StackDeduplicator
 }
 
 type SlogConvenience struct {
@@ -24,40 +62,65 @@ type SlogConvenience struct {
 
 	options SlogOptions
 	attrs   []slog.Attr
+
+	// The call sites that have been warned about the inapplicable control attributes,
+	// it's shared by all the derived handlers
+	warnedControls *sync.Map
 }
 
 var _ slog.Handler = &SlogConvenience{}
 
 func NewSlogConvenience(opts SlogOptions, delegate slog.Handler) *SlogConvenience {
 	return &SlogConvenience{
-		delegate: delegate,
-		options:  opts,
+		delegate:       delegate,
+		options:        opts,
+		warnedControls: &sync.Map{},
 	}
 }
 
 func (s *SlogConvenience) Enabled(ctx context.Context, level slog.Level) bool {
-	curLevel := s.options.LogLevel
-	return level >= curLevel
+	return level >= s.options.minLevel(ctx, 0)
 }
 
-func (s *SlogConvenience) Handle(ctx context.Context, record slog.Record) error {
-	// We can't really move this check to Enabled() because it's not really possible to get the
-	// calling function name without having access to the slog.Record.PC field.
-	if s.options.Pinpointer != nil {
-		forPC := runtime.FuncForPC(record.PC)
-		funcName := forPC.Name()
-		pinpointedLevel, ok := s.options.Pinpointer.LevelForLocation(funcName)
+// The minimum level for the logging call, the pc is 0 if the call site is not known. The level set
+// with WithLogLevel takes precedence, then LevelSource, NamedLevels, Leveler and LogLevel.
+func (o *SlogOptions) minLevel(ctx context.Context, pc uintptr) slog.Level {
+	if o.levelOverride {
+		return o.LogLevel
+	}
+	if o.LevelSource != nil {
+		lvl, ok := o.LevelSource.MinLevel(ctx, o.LoggerName, pc)
 		if ok {
-			if record.Level < pinpointedLevel {
-				return nil
-			}
+			return lvl
+		}
+	}
+	if o.NamedLevels != nil && o.LoggerName != "" {
+		namedLevel, ok := o.NamedLevels.LevelForName(o.LoggerName)
+		if ok {
+			return namedLevel
 		}
 	}
+	if o.Leveler != nil {
+		return o.Leveler.Level()
+	}
+	return o.LogLevel
+}
 
+func (s *SlogConvenience) Handle(ctx context.Context, record slog.Record) error {
+	// The control attributes passed to the logging call change the options just for this record
+	opts := &s.options
 	newAttrs := make([]slog.Attr, 0, record.NumAttrs())
 
 	var stackTrace *slog.Attr
 	record.Attrs(func(a slog.Attr) bool {
+		if ca, ok := a.Value.Any().(ControlAttr); ok {
+			opts = s.applyCallSiteControl(ctx, opts, &record, a.Key, ca)
+			return true
+		}
+		if a.Value.Kind() == slog.KindGroup {
+			a, opts, _ = s.stripGroupControls(ctx, opts, &record, a)
+		}
+
 		_, ok := a.Value.Any().(*StackValue)
 		if stackTrace == nil && a.Key == StackAttrName && ok {
 			stackTrace = &a
@@ -68,13 +131,53 @@ func (s *SlogConvenience) Handle(ctx context.Context, record slog.Record) error
 		return true
 	})
 
-	merged := s.mergeAttrs(newAttrs, s.attrs, s.options.AppendNewAttrsRight)
+	// The level source might decide differently once the call site is known
+	if opts.LevelSource != nil && record.Level < opts.minLevel(ctx, record.PC) {
+		return nil
+	}
+
+	// We can't really move this check to Enabled() because it's not really possible to get the
+	// calling function name without having access to the slog.Record.PC field.
+	if opts.Pinpointer != nil {
+		funcName := cachedFuncNameForPC(record.PC)
+		pinpointedLevel, ok := opts.Pinpointer.LevelForLocation(funcName)
+		if ok {
+			if record.Level < pinpointedLevel {
+				return nil
+			}
+		}
+	}
+
+	if opts.Sampler != nil && !opts.Sampler.Sample(ctx, record) {
+		return nil
+	}
+
+	merged := s.mergeAttrs(newAttrs, s.attrs, opts.AppendNewAttrsRight)
+
+	if stackTrace != nil && (opts.EmitStackID || opts.StackDedup != nil) {
+		stackID := stackTrace.Value.Any().(*StackValue).Fingerprint(opts.StackIDWithLines)
+		merged = append(merged, slog.String(StackIDAttrName, stackID))
+		if opts.StackDedup != nil && !opts.StackDedup.ShouldEmit(stackID, record.Time) {
+			stackTrace = nil
+		}
+	}
 
 	// Extract context attributes
-	for _, extractor := range s.options.Extractors {
+	for _, extractor := range opts.Extractors {
 		merged = extractor.MergeContextAttrs(ctx, merged)
 	}
 
+	// The LogValuers are resolved here to protect the delegate from panics
+	merged = safeResolveAttrs(merged, opts.ResolveTimeout)
+
+	if len(opts.Redactions) != 0 {
+		merged = redactAttrs(opts.Redactions, merged)
+	}
+
+	if opts.Limits != nil {
+		merged = opts.Limits.limitAttrs(merged)
+	}
+
 	mergedRecord := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
 	mergedRecord.AddAttrs(merged...)
 
@@ -91,6 +194,94 @@ func (s *SlogConvenience) Handle(ctx context.Context, record slog.Record) error
 	return err
 }
 
+type controlSite struct {
+	pc  uintptr
+	key string
+}
+
+// Apply the control attribute passed to the logging call, the options are copied before the first
+// change. The attributes that can't be applied to a single record are dropped, with a warning logged
+// once per call site.
+func (s *SlogConvenience) applyCallSiteControl(ctx context.Context, opts *SlogOptions, record *slog.Record,
+	key string, ca ControlAttr) *SlogOptions {
+
+	csa, ok := ca.(CallSiteControlAttr)
+	if ok {
+		if opts == &s.options {
+			opts = new(SlogOptions)
+			*opts = s.options
+		}
+		csa.ApplyToRecord(opts, record)
+		return opts
+	}
+
+	// The warning goes through the usual checks
+	if !s.Enabled(ctx, slog.LevelWarn) {
+		return opts
+	}
+	if _, warned := s.warnedControls.LoadOrStore(controlSite{pc: record.PC, key: key}, true); warned {
+		return opts
+	}
+	warning := slog.NewRecord(record.Time, slog.LevelWarn,
+		"The control attribute can't be applied to a single record, it's ignored", record.PC)
+	warning.AddAttrs(slog.String("control_attr", key))
+	_ = s.Handle(ctx, warning)
+	return opts
+}
+
+// Apply and remove the control attributes nested in the group passed to the logging call,
+// it returns false if the group has no control attributes
+func (s *SlogConvenience) stripGroupControls(ctx context.Context, opts *SlogOptions, record *slog.Record,
+	group slog.Attr) (slog.Attr, *SlogOptions, bool) {
+
+	attrs := group.Value.Group()
+	// The group is copied only if it has the control attributes
+	var stripped []slog.Attr
+	for i, a := range attrs {
+		if ca, ok := a.Value.Any().(ControlAttr); ok {
+			if stripped == nil {
+				stripped = slices.Clone(attrs[:i])
+			}
+			opts = s.applyCallSiteControl(ctx, opts, record, a.Key, ca)
+			continue
+		}
+
+		if a.Value.Kind() == slog.KindGroup {
+			var changed bool
+			a, opts, changed = s.stripGroupControls(ctx, opts, record, a)
+			if stripped == nil && changed {
+				stripped = slices.Clone(attrs[:i])
+			}
+		}
+		if stripped != nil {
+			stripped = append(stripped, a)
+		}
+	}
+
+	if stripped == nil {
+		return group, opts, false
+	}
+	return slog.Attr{Key: group.Key, Value: slog.GroupValue(stripped...)}, opts, true
+}
+
+// funcNameForPC returns the name of the function for the return address PC, as reported by
+// runtime.Callers. Unlike runtime.FuncForPC, this correctly handles the inlined calls.
+func funcNameForPC(pc uintptr) string {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return frame.Function
+}
+
+// The set of the logging call sites is limited, so the function names are cached forever
+var callSiteNames sync.Map
+
+func cachedFuncNameForPC(pc uintptr) string {
+	name, ok := callSiteNames.Load(pc)
+	if !ok {
+		name, _ = callSiteNames.LoadOrStore(pc, funcNameForPC(pc))
+	}
+	return name.(string)
+}
+
 func (s *SlogConvenience) mergeAttrs(newAttrs, curAttrs []slog.Attr, appendNewAttrsRight bool) []slog.Attr {
 	if len(newAttrs) == 0 {
 		return slices.Clone(curAttrs)
@@ -120,29 +311,28 @@ func (s *SlogConvenience) WithAttrs(attrs []slog.Attr) slog.Handler {
 		ca, ok := a.Value.Any().(ControlAttr)
 		if ok {
 			// This is a control attribute!
-			s.processControlAttribute(&newOptions, ca)
+			ca.Apply(&newOptions)
 			continue
 		}
 		attrsProcessed = append(attrsProcessed, a)
 	}
 
-	resAttrs := s.mergeAttrs(attrsProcessed, s.attrs, newOptions.AppendNewAttrsRight)
-
-	return &SlogConvenience{
-		delegate: s.delegate,
-		options:  newOptions,
-		attrs:    resAttrs,
+	curAttrs := s.attrs
+	if newOptions.LoggerName != s.options.LoggerName {
+		// The logger name replaces the name of the parent logger
+		curAttrs = slices.DeleteFunc(slices.Clone(curAttrs), func(a slog.Attr) bool {
+			return a.Key == LoggerAttrName
+		})
+		attrsProcessed = append(attrsProcessed, slog.String(LoggerAttrName, newOptions.LoggerName))
 	}
-}
 
-func (s *SlogConvenience) processControlAttribute(opts *SlogOptions, ca ControlAttr) {
-	switch v := ca.(type) {
-	case *AttrOrder:
-		opts.AppendNewAttrsRight = v.appendRight
-	case *AttrLevel:
-		opts.LogLevel = v.level
-	default:
-		panic("unknown control attribute")
+	resAttrs := s.mergeAttrs(attrsProcessed, curAttrs, newOptions.AppendNewAttrsRight)
+
+	return &SlogConvenience{
+		delegate:       s.delegate,
+		options:        newOptions,
+		attrs:          resAttrs,
+		warnedControls: s.warnedControls,
 	}
 }
 
@@ -156,8 +346,9 @@ func (s *SlogConvenience) WithGroup(name string) slog.Handler {
 	}
 
 	return &SlogConvenience{
-		delegate: s.delegate,
-		options:  s.options,
-		attrs:    newAttrs,
+		delegate:       s.delegate,
+		options:        s.options,
+		attrs:          newAttrs,
+		warnedControls: s.warnedControls,
 	}
 }
@@ -3,7 +3,9 @@ package tidbits
 import (
 	"github.com/stretchr/testify/assert"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAppendDirection(t *testing.T) {
@@ -63,3 +65,28 @@ func TestPinpointer(t *testing.T) {
 func interesting(log *slog.Logger) {
 	log.Info("interesting message")
 }
+
+func TestStackDeduplication(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	conv := slog.New(NewSlogConvenience(SlogOptions{
+		StackDedup: NewStackDeduplicator(time.Hour),
+	}, sink.Handler()))
+
+	opts := StackOptions{SkipTesting: true}
+	var ids []string
+	for i := 0; i < 3; i++ {
+		sv := NewStackValueWithOptions(1, opts, "failure")
+		ids = append(ids, sv.Fingerprint(false))
+		conv.Error("failed", slog.Any(StackAttrName, sv))
+	}
+	assert.Equal(t, ids[0], ids[1])
+	assert.Equal(t, ids[0], ids[2])
+
+	lines := strings.Split(sink.Get(), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Contains(t, lines[0], `"stack_id":"`+ids[0]+`","stack":[{"panic_msg":"failure"}`)
+	assert.Equal(t, `{"time":"","level":"ERROR","msg":"failed","stack_id":"`+ids[0]+`"}`, lines[1])
+	assert.Equal(t, lines[1], lines[2])
+}
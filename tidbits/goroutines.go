@@ -0,0 +1,256 @@
+package tidbits
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const GoroutinesAttrName = "goroutines"
+
+// GoroutineStack is the parsed stack of one goroutine
+type GoroutineStack struct {
+	ID    int64  `json:"id"`
+	State string `json:"state"`
+	// WaitMinutes is the time the goroutine has been blocked, the runtime reports it only
+	// if it's at least one minute
+	WaitMinutes int  `json:"wait_minutes,omitempty"`
+	Locked      bool `json:"locked,omitempty"`
+
+	Stack     []StackElement `json:"stack"`
+	CreatedBy *StackElement  `json:"created_by,omitempty"`
+	ParentID  int64          `json:"parent_id,omitempty"`
+}
+
+// GoroutineDump is a snapshot of the stacks of all the goroutines in the process
+type GoroutineDump struct {
+	goroutines []GoroutineStack
+}
+
+var _ json.Marshaler = &GoroutineDump{}
+var _ encoding.TextMarshaler = &GoroutineDump{}
+
+// GoroutinesAttr snapshots the stacks of all the goroutines. This is an expensive operation
+// that stops the world, so it should be used only for debugging deadlocks and hangs.
+func GoroutinesAttr() slog.Attr {
+	return slog.Any(GoroutinesAttrName, NewGoroutineDump())
+}
+
+func NewGoroutineDump() *GoroutineDump {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return &GoroutineDump{goroutines: ParseGoroutineDump(buf[:n])}
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+func (g *GoroutineDump) Goroutines() []GoroutineStack {
+	return g.goroutines
+}
+
+func (g *GoroutineDump) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.goroutines)
+}
+
+// MarshalText formats the goroutines in a human-readable multi-line string, similar
+// to the Go runtime dumps.
+func (g *GoroutineDump) MarshalText() (text []byte, err error) {
+	res := bytes.NewBuffer(nil)
+	for i, gr := range g.goroutines {
+		if i != 0 {
+			res.WriteString("\n")
+		}
+		res.WriteString(gr.Header() + "\n")
+		for _, e := range gr.Stack {
+			res.WriteString(e.String() + "\n")
+		}
+		if gr.CreatedBy != nil {
+			res.WriteString("created by " + gr.CreatedBy.String() + "\n")
+		}
+	}
+	return res.Bytes(), nil
+}
+
+// Header returns the goroutine description in the Go runtime format: "goroutine 1 [running]:"
+func (g *GoroutineStack) Header() string {
+	state := g.State
+	if g.WaitMinutes > 0 {
+		state += ", " + strconv.Itoa(g.WaitMinutes) + " minutes"
+	}
+	if g.Locked {
+		state += ", locked to thread"
+	}
+	return "goroutine " + strconv.FormatInt(g.ID, 10) + " [" + state + "]:"
+}
+
+// Example: "goroutine 18 [chan receive, 2 minutes]:", the runtime can also add extra
+// information between the ID and the state ("goroutine 1 gp=0xc000002380 m=0 mp=0x6 [running]:")
+var goroutineHeaderRe = regexp.MustCompile(`^goroutine (\d+)(?: [^\[]*)? \[(.*)\]:$`)
+
+// ParseGoroutineDump parses the text produced by runtime.Stack or by the Go runtime on crashes.
+// Lines that are not a part of a goroutine dump are ignored.
+func ParseGoroutineDump(dump []byte) []GoroutineStack {
+	parsed := parseTraceback(dump)
+	res := make([]GoroutineStack, 0, len(parsed))
+	for _, gr := range parsed {
+		for i := range gr.frames {
+			gr.Stack = append(gr.Stack, gr.frames[i].elem)
+		}
+		res = append(res, gr.GoroutineStack)
+	}
+	return res
+}
+
+// tracebackGoroutine is a parsed goroutine with the frames that still have the full function names
+type tracebackGoroutine struct {
+	GoroutineStack
+	frames []symbolFrame
+}
+
+func parseTraceback(dump []byte) []tracebackGoroutine {
+	var res []tracebackGoroutine
+	var cur *tracebackGoroutine
+	var pendingFunc string
+	var pendingCreatedBy bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(dump))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if m := goroutineHeaderRe.FindStringSubmatch(line); m != nil {
+			res = append(res, tracebackGoroutine{GoroutineStack: parseGoroutineHeader(m[1], m[2])})
+			cur = &res[len(res)-1]
+			pendingFunc = ""
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+
+		if line == "" {
+			// The goroutines are separated by empty lines
+			cur = nil
+			continue
+		}
+
+		if strings.HasPrefix(line, "\t") {
+			// This is the location line for the function from the previous line
+			if pendingFunc == "" {
+				continue
+			}
+			frame := parseTracebackLocation(pendingFunc, strings.TrimPrefix(line, "\t"))
+			if pendingCreatedBy {
+				cur.CreatedBy = &frame.elem
+			} else {
+				cur.frames = append(cur.frames, frame)
+			}
+			pendingFunc = ""
+			continue
+		}
+
+		if strings.HasPrefix(line, "...") {
+			// "...additional frames elided..."
+			continue
+		}
+
+		pendingCreatedBy = false
+		if createdBy, ok := strings.CutPrefix(line, "created by "); ok {
+			pendingCreatedBy = true
+			if fn, parent, ok := strings.Cut(createdBy, " in goroutine "); ok {
+				cur.ParentID, _ = strconv.ParseInt(parent, 10, 64)
+				createdBy = fn
+			}
+			pendingFunc = createdBy
+			continue
+		}
+
+		// Strip the arguments: "main.(*T).foo(0xc000012345, {0x1, 0x2})"
+		pendingFunc = line
+		if idx := strings.LastIndex(line, "("); idx > 0 && strings.HasSuffix(line, ")") {
+			pendingFunc = line[:idx]
+		}
+	}
+
+	return res
+}
+
+func parseGoroutineHeader(id, state string) GoroutineStack {
+	res := GoroutineStack{}
+	res.ID, _ = strconv.ParseInt(id, 10, 64)
+
+	parts := strings.Split(state, ", ")
+	res.State = parts[0]
+	for _, p := range parts[1:] {
+		if p == "locked to thread" {
+			res.Locked = true
+		} else if mins, ok := strings.CutSuffix(p, " minutes"); ok {
+			res.WaitMinutes, _ = strconv.Atoi(mins)
+		}
+	}
+	return res
+}
+
+// Parse the location line: "/usr/local/go/src/testing/testing.go:1689 +0x1d"
+func parseTracebackLocation(function, location string) symbolFrame {
+	location, _, _ = strings.Cut(location, " +0x")
+	file, line := location, 0
+	if idx := strings.LastIndex(location, ":"); idx != -1 {
+		file = location[:idx]
+		line, _ = strconv.Atoi(location[idx+1:])
+	}
+
+	// The runtime prints runtime.gopanic as just "panic"
+	if function == "panic" && strings.HasSuffix(file, "runtime/panic.go") {
+		function = "runtime.gopanic"
+	}
+
+	return parseFrame(runtime.Frame{Function: function, File: file, Line: line})
+}
+
+// LogGoroutinesOnSignal logs the stacks of all the goroutines through the logger each time
+// the process receives one of the signals (SIGQUIT if no signals are specified). This disables
+// the default Go behavior for SIGQUIT that dumps the stacks to stderr and exits the process.
+// Call the returned function to remove the handler, it can be called several times.
+func LogGoroutinesOnSignal(logger *slog.Logger, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGQUIT}
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigChan, signals...)
+
+	go func() {
+		for {
+			select {
+			case sig := <-sigChan:
+				logger.Error("Goroutine dump", slog.String("signal", sig.String()),
+					GoroutinesAttr())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sigChan)
+			close(done)
+		})
+	}
+}
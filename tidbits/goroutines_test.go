@@ -0,0 +1,112 @@
+package tidbits
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const sampleDump = `goroutine 1 [running]:
+main.main()
+	/home/user/project/main.go:12 +0x1d
+
+goroutine 18 [chan receive, 5 minutes, locked to thread]:
+github.com/Cyberax/slog-tidbits/tidbits.(*Worker).loop(0xc000012345, {0x1, 0x2})
+	/home/user/project/tidbits/worker.go:42 +0x8f
+created by main.main in goroutine 1
+	/home/user/project/main.go:10 +0x25
+`
+
+func TestParseGoroutineDump(t *testing.T) {
+	t.Parallel()
+
+	grs := ParseGoroutineDump([]byte(sampleDump))
+	assert.Equal(t, []GoroutineStack{
+		{
+			ID: 1, State: "running",
+			Stack: []StackElement{{Fl: "main/main.go:12", Fn: "main"}},
+		},
+		{
+			ID: 18, State: "chan receive", WaitMinutes: 5, Locked: true,
+			Stack: []StackElement{{Fl: "github.com/Cyberax/slog-tidbits/tidbits.(*Worker)/worker.go:42",
+				Fn: "loop"}},
+			CreatedBy: &StackElement{Fl: "main/main.go:10", Fn: "main"},
+			ParentID:  1,
+		},
+	}, grs)
+
+	text, err := (&GoroutineDump{goroutines: grs}).MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, `goroutine 1 [running]:
+main/main.go:12 main
+
+goroutine 18 [chan receive, 5 minutes, locked to thread]:
+github.com/Cyberax/slog-tidbits/tidbits.(*Worker)/worker.go:42 loop
+created by main/main.go:10 main
+`, string(text))
+}
+
+func TestGoroutinesAttr(t *testing.T) {
+	t.Parallel()
+
+	blocker := make(chan struct{})
+	defer close(blocker)
+	go blockedGoroutine(blocker)
+
+	// Wait for the goroutine to get blocked
+	assert.Eventually(t, func() bool {
+		for _, gr := range NewGoroutineDump().Goroutines() {
+			if len(gr.Stack) > 0 && gr.Stack[0].Fn == "blockedGoroutine" {
+				assert.Equal(t, "chan receive", gr.State)
+				assert.Equal(t, "TestGoroutinesAttr", gr.CreatedBy.Fn)
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func blockedGoroutine(blocker chan struct{}) {
+	<-blocker
+}
+
+func TestPrettySinkGoroutines(t *testing.T) {
+	t.Parallel()
+
+	data := &bytes.Buffer{}
+	pretty := NewPrettySink(data, slog.LevelInfo, false)
+
+	conv := slog.New(pretty.GetHandler())
+	conv.Error("Dump", slog.Any(GoroutinesAttrName,
+		&GoroutineDump{goroutines: ParseGoroutineDump([]byte(sampleDump))}))
+
+	expected := `ERROR  goroutines_test.go:85  Dump
+	goroutine 1 [running]:
+		main/main.go:12 (main)
+	goroutine 18 [chan receive, 5 minutes, locked to thread]:
+		github.com/Cyberax/slog-tidbits/tidbits.(*Worker)/worker.go:42 (loop)
+		created by main/main.go:10 (main)`
+	assert.Equal(t, expected, removeTimes(data.String()))
+}
+
+func TestLogGoroutinesOnSignal(t *testing.T) {
+	sink := NewSinkingLogger(slog.LevelInfo)
+	stop := LogGoroutinesOnSignal(sink.Logger)
+	defer stop()
+
+	proc, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+	assert.NoError(t, proc.Signal(syscall.SIGQUIT))
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(sink.Get(), `"msg":"Goroutine dump","signal":"quit"`)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The deferred call is a no-op
+	stop()
+}
@@ -0,0 +1,185 @@
+package tidbits
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+type JournaldOptions struct {
+	Level slog.Leveler
+	// SocketPath is the journald native protocol socket, DefaultJournaldSocket if empty
+	SocketPath string
+	// SyslogIdentifier is sent as the SYSLOG_IDENTIFIER field, the process name by default
+	SyslogIdentifier string
+}
+
+// JournaldHandler sends the records to journald using its native protocol. The attribute keys
+// are upper-cased (with "_" instead of the invalid characters), and the groups are joined with "_".
+type JournaldHandler struct {
+	opts JournaldOptions
+	conn *net.UnixConn
+
+	prefix    string
+	preformed []byte
+}
+
+var _ slog.Handler = &JournaldHandler{}
+
+func NewJournaldHandler(opts *JournaldOptions) (*JournaldHandler, error) {
+	h := &JournaldHandler{}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelInfo
+	}
+	if h.opts.SocketPath == "" {
+		h.opts.SocketPath = DefaultJournaldSocket
+	}
+	if h.opts.SyslogIdentifier == "" {
+		h.opts.SyslogIdentifier = filepath.Base(os.Args[0])
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: h.opts.SocketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	h.conn = conn
+	return h, nil
+}
+
+// Close closes the journald socket
+func (h *JournaldHandler) Close() error {
+	return h.conn.Close()
+}
+
+func (h *JournaldHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+func (h *JournaldHandler) Handle(ctx context.Context, record slog.Record) error {
+	buf := bytes.NewBuffer(make([]byte, 0, 512))
+
+	appendJournaldField(buf, "MESSAGE", record.Message)
+	appendJournaldField(buf, "PRIORITY", strconv.Itoa(SyslogSeverity(record.Level)))
+	appendJournaldField(buf, "SYSLOG_IDENTIFIER", h.opts.SyslogIdentifier)
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		appendJournaldField(buf, "CODE_FILE", frame.File)
+		appendJournaldField(buf, "CODE_LINE", strconv.Itoa(frame.Line))
+		appendJournaldField(buf, "CODE_FUNC", frame.Function)
+	}
+
+	buf.Write(h.preformed)
+	record.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(buf, h.prefix, a)
+		return true
+	})
+
+	_, err := h.conn.Write(buf.Bytes())
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		// The message is too large for a datagram, pass it through a sealed memory file
+		return sendJournaldMemfd(h.conn, buf.Bytes())
+	}
+	return err
+}
+
+func (h *JournaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	buf := bytes.NewBuffer(slices.Clone(h.preformed))
+	for _, a := range attrs {
+		h.appendAttr(buf, h.prefix, a)
+	}
+	res := *h
+	res.preformed = buf.Bytes()
+	return &res
+}
+
+func (h *JournaldHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	res := *h
+	res.prefix = h.prefix + name + "_"
+	return &res
+}
+
+func (h *JournaldHandler) appendAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	if sv, ok := a.Value.Any().(*StackValue); ok && a.Value.Kind() == slog.KindLogValuer {
+		// The binary field encoding keeps the multi-line stack intact
+		text, _ := sv.MarshalText()
+		appendJournaldField(buf, journaldFieldName(prefix+a.Key), string(text))
+		return
+	}
+
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "_"
+		}
+		for _, ga := range a.Value.Group() {
+			h.appendAttr(buf, groupPrefix, ga)
+		}
+		return
+	}
+
+	appendJournaldField(buf, journaldFieldName(prefix+a.Key), formatLogfmtValue(a.Value))
+}
+
+// Append the field as `KEY=value\n`, or as `KEY\n<64-bit LE length>value\n` if the value
+// contains newlines
+func appendJournaldField(buf *bytes.Buffer, key, val string) {
+	buf.WriteString(key)
+	if !strings.ContainsRune(val, '\n') {
+		buf.WriteByte('=')
+		buf.WriteString(val)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(val)))
+	buf.WriteString(val)
+	buf.WriteByte('\n')
+}
+
+// The field names can only contain A-Z, 0-9 and '_', can't start with '_' (reserved for the
+// trusted fields) or a digit, and are limited to 64 characters
+func journaldFieldName(key string) string {
+	res := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	res = strings.TrimLeft(res, "_")
+	if res == "" || res[0] >= '0' && res[0] <= '9' {
+		res = "F_" + res
+	}
+	if len(res) > 64 {
+		res = res[:64]
+	}
+	return res
+}
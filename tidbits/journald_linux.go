@@ -0,0 +1,40 @@
+package tidbits
+
+import (
+	"golang.org/x/sys/unix"
+	"net"
+	"os"
+)
+
+// Send the message through a sealed memfd, journald reads the whole file as the message
+func sendJournaldMemfd(conn *net.UnixConn, payload []byte) error {
+	fd, err := unix.MemfdCreate("journal-message", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "journal-message")
+	defer func() { _ = file.Close() }()
+
+	if _, err = file.Write(payload); err != nil {
+		return err
+	}
+	_, err = unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS,
+		unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL)
+	if err != nil {
+		return err
+	}
+
+	// WriteMsgUnix refuses to send to the connected datagram socket, so use sendmsg directly
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	ctlErr := raw.Write(func(sock uintptr) bool {
+		err = unix.Sendmsg(int(sock), nil, unix.UnixRights(fd), nil, 0)
+		return err != unix.EAGAIN
+	})
+	if ctlErr != nil {
+		return ctlErr
+	}
+	return err
+}
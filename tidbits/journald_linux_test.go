@@ -0,0 +1,116 @@
+package tidbits
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournaldFields(t *testing.T) {
+	t.Parallel()
+
+	conn, path := listenJournald(t)
+	h, err := NewJournaldHandler(&JournaldOptions{SocketPath: path, SyslogIdentifier: "app"})
+	assert.NoError(t, err)
+	defer func() { _ = h.Close() }()
+
+	logger := slog.New(h)
+	logger.With(slog.String("component", "db")).WithGroup("req").Warn("hello",
+		slog.Int("id", 1), slog.String("bad-key", "multi\nline"),
+		StackTraceAttrWithOptions(StackOptions{SkipTesting: true}, "boom"))
+
+	fields := readJournald(t, conn)
+	assert.Equal(t, "hello", fields["MESSAGE"])
+	assert.Equal(t, "4", fields["PRIORITY"])
+	assert.Equal(t, "app", fields["SYSLOG_IDENTIFIER"])
+	assert.True(t, strings.HasSuffix(fields["CODE_FILE"], "tidbits/journald_linux_test.go"))
+	assert.Equal(t, "27", fields["CODE_LINE"])
+	assert.Equal(t, "github.com/Cyberax/slog-tidbits/tidbits.TestJournaldFields", fields["CODE_FUNC"])
+	assert.Equal(t, "db", fields["COMPONENT"])
+	assert.Equal(t, "1", fields["REQ_ID"])
+	assert.Equal(t, "multi\nline", fields["REQ_BAD_KEY"])
+	assert.Equal(t, "boom\n"+
+		"github.com/Cyberax/slog-tidbits/tidbits/stacks.go:61 StackTraceAttrWithOptions\n"+
+		"github.com/Cyberax/slog-tidbits/tidbits/journald_linux_test.go:29 TestJournaldFields\n",
+		fields["REQ_STACK"])
+}
+
+func TestJournaldMemfd(t *testing.T) {
+	t.Parallel()
+
+	conn, path := listenJournald(t)
+	h, err := NewJournaldHandler(&JournaldOptions{SocketPath: path})
+	assert.NoError(t, err)
+	defer func() { _ = h.Close() }()
+
+	large := strings.Repeat("x", 4*1024*1024)
+	slog.New(h).Info("large", slog.String("payload", large))
+
+	fields := readJournald(t, conn)
+	assert.Equal(t, "large", fields["MESSAGE"])
+	assert.Equal(t, large, fields["PAYLOAD"])
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "HELLO_WORLD", journaldFieldName("hello.world"))
+	assert.Equal(t, "TRUSTED", journaldFieldName("_trusted"))
+	assert.Equal(t, "F_1ST", journaldFieldName("1st"))
+	assert.Equal(t, "F_", journaldFieldName(""))
+	assert.Equal(t, 64, len(journaldFieldName(strings.Repeat("a", 100))))
+}
+
+func listenJournald(t *testing.T) (*net.UnixConn, string) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, path
+}
+
+// Read the datagram (or the passed memfd) and parse the native protocol fields
+func readJournald(t *testing.T, conn *net.UnixConn) map[string]string {
+	buf := make([]byte, 256*1024)
+	oob := make([]byte, unix.CmsgSpace(4))
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	assert.NoError(t, err)
+	data := buf[:n]
+
+	if oobn != 0 {
+		msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+		assert.NoError(t, err)
+		fds, err := unix.ParseUnixRights(&msgs[0])
+		assert.NoError(t, err)
+		file := os.NewFile(uintptr(fds[0]), "memfd")
+		defer func() { _ = file.Close() }()
+		_, _ = file.Seek(0, io.SeekStart)
+		data, err = io.ReadAll(file)
+		assert.NoError(t, err)
+	}
+
+	fields := map[string]string{}
+	for len(data) > 0 {
+		eol := bytes.IndexByte(data, '\n')
+		line := string(data[:eol])
+		data = data[eol+1:]
+		if key, val, ok := strings.Cut(line, "="); ok {
+			fields[key] = val
+			continue
+		}
+		size := binary.LittleEndian.Uint64(data)
+		fields[line] = string(data[8 : 8+size])
+		data = data[8+size+1:]
+	}
+	return fields
+}
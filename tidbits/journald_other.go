@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tidbits
+
+import (
+	"net"
+	"syscall"
+)
+
+// The memfd fallback is only available on Linux
+func sendJournaldMemfd(conn *net.UnixConn, payload []byte) error {
+	return syscall.EMSGSIZE
+}
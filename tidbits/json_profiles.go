@@ -0,0 +1,252 @@
+package tidbits
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ProfileOptions configures the JSON output profiles for the specific log backends
+type ProfileOptions struct {
+	Level     slog.Leveler
+	AddSource bool
+
+	// LevelNames overrides the backend level names for the specific levels
+	// (e.g. {lhelper.LevelTrace: "trace"})
+	LevelNames map[slog.Level]string
+
+	// The attribute names for the tracing IDs, "trace_id" and "span_id" by default (as emitted
+	// by the otel.TracingIdExtractor)
+	TraceIDKey string
+	SpanIDKey  string
+	// TraceGroup is the group with the tracing IDs, if the otel.TracingIdExtractor is configured
+	// with a Group. The IDs are moved out of the group into the backend fields, the other tracing
+	// attributes stay in the group.
+	TraceGroup string
+
+	// ProjectID is the Google Cloud project ID, it's used to create the full trace
+	// resource name ("projects/<ProjectID>/traces/<TraceID>") for Cloud Logging
+	ProjectID string
+}
+
+func (p *ProfileOptions) traceKeys() (string, string) {
+	traceKey, spanKey := p.TraceIDKey, p.SpanIDKey
+	if traceKey == "" {
+		traceKey = "trace_id"
+	}
+	if spanKey == "" {
+		spanKey = "span_id"
+	}
+	return traceKey, spanKey
+}
+
+// NewECSHandler creates a JSON handler that writes the records using the Elastic Common Schema
+// field names: `@timestamp`, `log.level`, `message`, `log.origin`, `trace.id`, `span.id`
+// and `error.stack_trace`.
+func NewECSHandler(out io.Writer, opts *ProfileOptions) slog.Handler {
+	if opts == nil {
+		opts = &ProfileOptions{}
+	}
+	traceKey, spanKey := opts.traceKeys()
+
+	return opts.withTraceGroup(slog.NewJSONHandler(out, &slog.HandlerOptions{
+		Level:     opts.Level,
+		AddSource: opts.AddSource,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) != 0 {
+				return a
+			}
+			switch a.Key {
+			case slog.TimeKey:
+				a.Key = "@timestamp"
+			case slog.LevelKey:
+				return slog.String("log.level", ecsLevelName(opts.LevelNames, a.Value))
+			case slog.MessageKey:
+				a.Key = "message"
+			case slog.SourceKey:
+				src, ok := a.Value.Any().(*slog.Source)
+				if !ok {
+					return a
+				}
+				return slog.Group("log.origin",
+					slog.Group("file", slog.String("name", src.File), slog.Int("line", src.Line)),
+					slog.String("function", src.Function))
+			case traceKey:
+				a.Key = "trace.id"
+			case spanKey:
+				a.Key = "span.id"
+			case StackAttrName:
+				return stackAsText("error.stack_trace", a)
+			}
+			return a
+		},
+	}))
+}
+
+// NewCloudLoggingHandler creates a JSON handler that writes the records in the Google Cloud Logging
+// structured format: `severity`, `message`, `logging.googleapis.com/sourceLocation`,
+// `logging.googleapis.com/trace`, `logging.googleapis.com/spanId` and `stack_trace`.
+func NewCloudLoggingHandler(out io.Writer, opts *ProfileOptions) slog.Handler {
+	if opts == nil {
+		opts = &ProfileOptions{}
+	}
+	traceKey, spanKey := opts.traceKeys()
+
+	return opts.withTraceGroup(slog.NewJSONHandler(out, &slog.HandlerOptions{
+		Level:     opts.Level,
+		AddSource: opts.AddSource,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) != 0 {
+				return a
+			}
+			switch a.Key {
+			case slog.LevelKey:
+				return slog.String("severity", cloudLoggingSeverity(opts.LevelNames, a.Value))
+			case slog.MessageKey:
+				a.Key = "message"
+			case slog.SourceKey:
+				src, ok := a.Value.Any().(*slog.Source)
+				if !ok {
+					return a
+				}
+				return slog.Group("logging.googleapis.com/sourceLocation",
+					slog.String("file", src.File), slog.Int("line", src.Line),
+					slog.String("function", src.Function))
+			case traceKey:
+				traceID := a.Value.String()
+				if opts.ProjectID != "" {
+					traceID = "projects/" + opts.ProjectID + "/traces/" + traceID
+				}
+				return slog.String("logging.googleapis.com/trace", traceID)
+			case spanKey:
+				a.Key = "logging.googleapis.com/spanId"
+			case StackAttrName:
+				return stackAsText("stack_trace", a)
+			}
+			return a
+		},
+	}))
+}
+
+func (p *ProfileOptions) withTraceGroup(h slog.Handler) slog.Handler {
+	if p.TraceGroup == "" {
+		return h
+	}
+	traceKey, spanKey := p.traceKeys()
+	return &traceGroupHandler{Handler: h, group: p.TraceGroup, traceKey: traceKey, spanKey: spanKey}
+}
+
+// traceGroupHandler moves the tracing IDs out of their group to the top level, ReplaceAttr can
+// only rename them within the group
+type traceGroupHandler struct {
+	slog.Handler
+	group, traceKey, spanKey string
+	// The records inside the groups are passed as is
+	inGroup bool
+}
+
+var _ slog.Handler = &traceGroupHandler{}
+
+func (h *traceGroupHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.inGroup {
+		return h.Handler.Handle(ctx, record)
+	}
+
+	res := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		a.Value = a.Value.Resolve()
+		if a.Key != h.group || a.Value.Kind() != slog.KindGroup {
+			res.AddAttrs(a)
+			return true
+		}
+		var rest []slog.Attr
+		for _, ga := range a.Value.Group() {
+			if ga.Key == h.traceKey || ga.Key == h.spanKey {
+				res.AddAttrs(ga)
+			} else {
+				rest = append(rest, ga)
+			}
+		}
+		if len(rest) != 0 {
+			res.AddAttrs(slog.Attr{Key: a.Key, Value: slog.GroupValue(rest...)})
+		}
+		return true
+	})
+	return h.Handler.Handle(ctx, res)
+}
+
+func (h *traceGroupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	res := *h
+	res.Handler = h.Handler.WithAttrs(attrs)
+	return &res
+}
+
+func (h *traceGroupHandler) WithGroup(name string) slog.Handler {
+	res := *h
+	res.Handler = h.Handler.WithGroup(name)
+	res.inGroup = res.inGroup || name != ""
+	return &res
+}
+
+// The backends expect the stack traces as multi-line strings
+func stackAsText(key string, a slog.Attr) slog.Attr {
+	sv, ok := a.Value.Any().(*stackView)
+	if !ok {
+		return a
+	}
+	text, _ := (*StackValue)(sv).MarshalText()
+	return slog.String(key, string(text))
+}
+
+func levelOf(v slog.Value) (slog.Level, bool) {
+	lvl, ok := v.Any().(slog.Level)
+	return lvl, ok
+}
+
+func ecsLevelName(names map[slog.Level]string, v slog.Value) string {
+	lvl, ok := levelOf(v)
+	if !ok {
+		return strings.ToLower(v.String())
+	}
+	if name, ok := names[lvl]; ok {
+		return name
+	}
+	switch {
+	case lvl < slog.LevelDebug:
+		return "trace"
+	case lvl < slog.LevelInfo:
+		return "debug"
+	case lvl < slog.LevelWarn:
+		return "info"
+	case lvl < slog.LevelError:
+		return "warn"
+	case lvl < slog.LevelError+4:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
+
+// See https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogSeverity
+func cloudLoggingSeverity(names map[slog.Level]string, v slog.Value) string {
+	lvl, ok := levelOf(v)
+	if !ok {
+		return v.String()
+	}
+	if name, ok := names[lvl]; ok {
+		return name
+	}
+	switch {
+	case lvl < slog.LevelInfo:
+		return "DEBUG"
+	case lvl < slog.LevelWarn:
+		return "INFO"
+	case lvl < slog.LevelError:
+		return "WARNING"
+	case lvl < slog.LevelError+4:
+		return "ERROR"
+	default:
+		return "CRITICAL"
+	}
+}
@@ -0,0 +1,89 @@
+package tidbits
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var timestampRe = regexp.MustCompile(`"(@timestamp|time)":"[^"]*"`)
+
+func TestECSProfile(t *testing.T) {
+	t.Parallel()
+
+	data := &bytes.Buffer{}
+	logger := slog.New(NewECSHandler(data, &ProfileOptions{
+		Level:      slog.Level(-10),
+		AddSource:  true,
+		LevelNames: map[slog.Level]string{slog.Level(-10): "verbose"},
+	}))
+
+	logger.Log(context.Background(), slog.Level(-10), "tracing")
+	logger.Debug("debugging", slog.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+		slog.String("span_id", "00f067aa0ba902b7"))
+	logger.Error("failed", StackTraceAttrWithOptions(StackOptions{SkipTesting: true}, "boom"))
+
+	lines := strings.Split(timestampRe.ReplaceAllString(data.String(), `"$1":""`), "\n")
+	assert.Equal(t, `{"@timestamp":"","log.level":"verbose","log.origin":{"file":{"name":`+
+		`".../tidbits/json_profiles_test.go","line":25},"function":"github.com/Cyberax/slog-tidbits/`+
+		`tidbits.TestECSProfile"},"message":"tracing"}`, replaceSourceDir(lines[0]))
+	assert.Contains(t, lines[1], `"log.level":"debug"`)
+	assert.Contains(t, lines[1], `"message":"debugging","trace.id":"4bf92f3577b34da6a3ce929d0e0e4736",`+
+		`"span.id":"00f067aa0ba902b7"}`)
+	assert.Contains(t, lines[2], `"log.level":"error"`)
+	assert.Contains(t, lines[2], `"message":"failed","error.stack_trace":"boom\n`+
+		`github.com/Cyberax/slog-tidbits/tidbits/stacks.go:61 StackTraceAttrWithOptions\n`+
+		`github.com/Cyberax/slog-tidbits/tidbits/json_profiles_test.go:28 TestECSProfile\n"}`)
+}
+
+func TestCloudLoggingProfile(t *testing.T) {
+	t.Parallel()
+
+	data := &bytes.Buffer{}
+	logger := slog.New(NewCloudLoggingHandler(data, &ProfileOptions{Level: slog.LevelDebug, ProjectID: "proj"}))
+
+	logger.Debug("debugging")
+	logger.Warn("warning", slog.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+		slog.String("span_id", "00f067aa0ba902b7"))
+	logger.Log(context.Background(), slog.LevelError+4, "critical")
+
+	assert.Equal(t, `{"time":"","severity":"DEBUG","message":"debugging"}
+{"time":"","severity":"WARNING","message":"warning",`+
+		`"logging.googleapis.com/trace":"projects/proj/traces/4bf92f3577b34da6a3ce929d0e0e4736",`+
+		`"logging.googleapis.com/spanId":"00f067aa0ba902b7"}
+{"time":"","severity":"CRITICAL","message":"critical"}
+`, timestampRe.ReplaceAllString(data.String(), `"$1":""`))
+}
+
+func TestProfilesTraceGroup(t *testing.T) {
+	t.Parallel()
+
+	data := &bytes.Buffer{}
+	opts := &ProfileOptions{TraceIDKey: "id", TraceGroup: "trace"}
+	ecs := slog.New(NewECSHandler(data, opts))
+	cloud := slog.New(NewCloudLoggingHandler(data, opts))
+
+	// The attributes as emitted by the otel.TracingIdExtractor with the "trace" group
+	tracing := slog.Group("trace", slog.String("id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+		slog.String("span_id", "00f067aa0ba902b7"), slog.Bool("sampled", true))
+	ecs.Info("ecs", tracing)
+	cloud.Info("cloud", tracing)
+	ecs.WithGroup("req").Info("grouped", tracing)
+
+	assert.Equal(t, `{"@timestamp":"","log.level":"info","message":"ecs","trace.id":"4bf92f3577b34da6a3ce929d0e0e4736",`+
+		`"span.id":"00f067aa0ba902b7","trace":{"sampled":true}}
+{"time":"","severity":"INFO","message":"cloud","logging.googleapis.com/trace":"4bf92f3577b34da6a3ce929d0e0e4736",`+
+		`"logging.googleapis.com/spanId":"00f067aa0ba902b7","trace":{"sampled":true}}
+{"@timestamp":"","log.level":"info","message":"grouped","req":{"trace":{"id":"4bf92f3577b34da6a3ce929d0e0e4736",`+
+		`"span_id":"00f067aa0ba902b7","sampled":true}}}
+`, timestampRe.ReplaceAllString(data.String(), `"$1":""`))
+}
+
+// Remove the checkout directory from the source file path
+func replaceSourceDir(line string) string {
+	return regexp.MustCompile(`"name":"[^"]*/tidbits/`).ReplaceAllString(line, `"name":".../tidbits/`)
+}
@@ -0,0 +1,64 @@
+package tidbits
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// LazyValue is computed when the record is handled, after SlogConvenience has applied the level,
+// pinpoint and sampling checks. The value is computed only once, even if the record is passed
+// to several handlers. If fn panics, all the handlers get the same "!PANIC(msg)" value.
+type LazyValue struct {
+	once  sync.Once
+	fn    func() any
+	value slog.Value
+}
+
+var _ slog.LogValuer = &LazyValue{}
+
+func NewLazyValue(fn func() any) *LazyValue {
+	return &LazyValue{fn: fn}
+}
+
+func (l *LazyValue) LogValue() slog.Value {
+	l.once.Do(func() {
+		// The panic is recovered here, so the value is set even if the handlers don't recover
+		l.value = protectedLogValue(lazyFunc(l.fn))
+		l.fn = nil
+	})
+	return l.value
+}
+
+type lazyFunc func() any
+
+func (f lazyFunc) LogValue() slog.Value {
+	return slog.AnyValue(f())
+}
+
+// Lazy creates the attribute with the value computed by fn only if the record is logged
+func Lazy(key string, fn func() any) slog.Attr {
+	return slog.Any(key, NewLazyValue(fn))
+}
+
+// JSONLazy creates the attribute with the JSON representation of the value computed by fn,
+// the value is computed and marshalled only if the record is logged. The JSON is kept as
+// json.RawMessage, so the JSON handlers embed it as is.
+func JSONLazy(key string, fn func() any) slog.Attr {
+	return Lazy(key, func() any {
+		res, err := json.Marshal(fn())
+		if err != nil {
+			return fmt.Sprintf("!ERROR(%s)", err.Error())
+		}
+		return json.RawMessage(res)
+	})
+}
+
+// Hex creates the attribute with the hex-encoded data, it's encoded only if the record is logged
+func Hex(key string, data []byte) slog.Attr {
+	return Lazy(key, func() any {
+		return hex.EncodeToString(data)
+	})
+}
@@ -0,0 +1,104 @@
+package tidbits
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// The handler that passes the records to several handlers
+type fanoutHandler []slog.Handler
+
+func (f fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return true
+}
+
+func (f fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	for _, h := range f {
+		_ = h.Handle(ctx, record.Clone())
+	}
+	return nil
+}
+
+func (f fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	res := make(fanoutHandler, 0, len(f))
+	for _, h := range f {
+		res = append(res, h.WithAttrs(attrs))
+	}
+	return res
+}
+
+func (f fanoutHandler) WithGroup(name string) slog.Handler {
+	res := make(fanoutHandler, 0, len(f))
+	for _, h := range f {
+		res = append(res, h.WithGroup(name))
+	}
+	return res
+}
+
+func TestLazy(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	payload := func() any {
+		calls++
+		return map[string]int{"a": 1}
+	}
+
+	json1 := NewSinkingLogger(slog.LevelDebug)
+	json2 := NewSinkingLogger(slog.LevelDebug)
+	pinpoint := NewPinpointLogLevels().WithOverride(slog.LevelInfo, "github.com/Cyberax/slog-tidbits/tidbits")
+	logger := slog.New(NewSlogConvenience(SlogOptions{LogLevel: slog.LevelDebug, Pinpointer: pinpoint},
+		fanoutHandler{json1.Handler(), json2.Handler()}))
+
+	logger.Debug("pinpointed", Lazy("v", payload), JSONLazy("j", payload))
+	sampler := NewEveryNthSampler(2, slog.LevelInfo)
+	assert.True(t, sampler.Sample(context.Background(), slog.Record{}))
+	logger.With(WithSampling(sampler)).Info("sampled", Lazy("v", payload))
+	assert.Equal(t, 0, calls)
+
+	logger.Info("logged", Lazy("v", payload), JSONLazy("j", payload), Hex("h", []byte{0xca, 0xfe}))
+	assert.Equal(t, 2, calls)
+
+	res := json1.Get()
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"logged","v":{"a":1},"j":{"a":1},"h":"cafe"}`, res)
+	assert.Equal(t, res, json2.Get())
+}
+
+func TestLazyWithoutConvenience(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	lazy := Lazy("v", func() any {
+		calls++
+		return 42
+	})
+
+	sink1 := NewSinkingLogger(slog.LevelInfo)
+	sink2 := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(fanoutHandler{sink1.Handler(), sink2.Handler()})
+	logger.Info("logged", lazy)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"logged","v":42}`, sink2.Get())
+}
+
+func TestLazyPanic(t *testing.T) {
+	t.Parallel()
+
+	lazy := Lazy("v", func() any {
+		panic("boom")
+	})
+
+	sink1 := NewSinkingLogger(slog.LevelInfo)
+	sink2 := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(fanoutHandler{sink1.Handler(), sink2.Handler()})
+	logger.Info("logged", lazy)
+
+	res := sink1.Get()
+	assert.True(t, strings.HasPrefix(res,
+		`{"time":"","level":"INFO","msg":"logged","v":{"panic":"!PANIC(boom)","stack":[{"panic_msg":"boom"}`), res)
+	assert.Equal(t, res, sink2.Get())
+}
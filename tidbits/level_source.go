@@ -0,0 +1,64 @@
+package tidbits
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LevelSource provides the minimum level at the time of logging, so it can consult the context or
+// the external configuration. It must be safe for concurrent use.
+type LevelSource interface {
+	// MinLevel returns the minimum level for the logger with the given name (empty for the unnamed
+	// loggers), or false to fall back to the configured levels. The pc is 0 when the call site is
+	// not known yet (in Enabled), the source is then asked again with the call site before the record
+	// is handled. The second answer can only drop the record, not enable the levels disabled by
+	// the first one.
+	MinLevel(ctx context.Context, loggerName string, pc uintptr) (slog.Level, bool)
+}
+
+// LevelSourceFunc adapts a function to the LevelSource interface
+type LevelSourceFunc func(ctx context.Context, loggerName string, pc uintptr) (slog.Level, bool)
+
+var _ LevelSource = LevelSourceFunc(nil)
+
+func (f LevelSourceFunc) MinLevel(ctx context.Context, loggerName string, pc uintptr) (slog.Level, bool) {
+	return f(ctx, loggerName, pc)
+}
+
+type contextLevelKey struct{}
+
+// WithContextLevel sets the minimum level for the logging calls with this context, it's used
+// by ContextLevelSource (e.g. to enable the debug logging for a single request)
+func WithContextLevel(ctx context.Context, lvl slog.Leveler) context.Context {
+	return context.WithValue(ctx, contextLevelKey{}, lvl)
+}
+
+// ContextLevelSource returns the level set by WithContextLevel
+func ContextLevelSource() LevelSource {
+	return LevelSourceFunc(func(ctx context.Context, loggerName string, pc uintptr) (slog.Level, bool) {
+		if ctx == nil {
+			return 0, false
+		}
+		lvl, ok := ctx.Value(contextLevelKey{}).(slog.Leveler)
+		if !ok {
+			return 0, false
+		}
+		return lvl.Level(), true
+	})
+}
+
+// PinpointLevelSource returns the level of the pinpoint rule for the call site. The pinpoint rules
+// can't apply before the call site is known, so the level is not decided for the calls with pc=0.
+// As a result, the rules can only raise the level above the configured one (e.g. to silence a noisy
+// package), the levels below the configured one stay disabled.
+func PinpointLevelSource(pinpointer *PinpointLogLevels) LevelSource {
+	if pinpointer == nil {
+		panic("the pinpoint levels are nil")
+	}
+	return LevelSourceFunc(func(ctx context.Context, loggerName string, pc uintptr) (slog.Level, bool) {
+		if pc == 0 {
+			return 0, false
+		}
+		return pinpointer.LevelForLocation(cachedFuncNameForPC(pc))
+	})
+}
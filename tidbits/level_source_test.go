@@ -0,0 +1,80 @@
+package tidbits
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+func TestLevelVar(t *testing.T) {
+	t.Parallel()
+
+	var lvl slog.LevelVar
+	sink := NewSinkingLogger(slog.LevelDebug)
+	root := slog.New(NewSlogConvenience(SlogOptions{Leveler: &lvl}, sink.Handler()))
+	child := root.With("a", 1)
+	pinned := root.With(WithLogLevel(slog.LevelWarn))
+
+	child.Debug("suppressed")
+	lvl.Set(slog.LevelDebug)
+	child.Debug("enabled")
+	pinned.Info("pinned")
+
+	assert.Equal(t, `{"time":"","level":"DEBUG","msg":"enabled","a":1}`, sink.Get())
+}
+
+func TestContextLevelSource(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelDebug)
+	logger := slog.New(NewSlogConvenience(SlogOptions{
+		LogLevel:    slog.LevelInfo,
+		LevelSource: ContextLevelSource(),
+	}, sink.Handler()))
+
+	ctx := WithContextLevel(context.Background(), slog.LevelDebug)
+	logger.DebugContext(context.Background(), "suppressed")
+	logger.DebugContext(ctx, "enabled")
+
+	assert.Equal(t, `{"time":"","level":"DEBUG","msg":"enabled"}`, sink.Get())
+}
+
+func TestPinpointLevelSource(t *testing.T) {
+	t.Parallel()
+
+	pinpoint := NewPinpointLogLevels().WithOverride(slog.LevelError, "github.com/Cyberax/slog-tidbits/tidbits")
+	sink := NewSinkingLogger(slog.LevelDebug)
+	logger := slog.New(NewSlogConvenience(SlogOptions{
+		LogLevel:    slog.LevelInfo,
+		LevelSource: PinpointLevelSource(pinpoint),
+	}, sink.Handler()))
+
+	logger.Warn("suppressed")
+	logger.Error("logged")
+	assert.Equal(t, `{"time":"","level":"ERROR","msg":"logged"}`, sink.Get())
+
+	// The pinpoint rules can't enable the levels below the configured one
+	pinpoint.WithOverride(slog.LevelDebug, "github.com/Cyberax/slog-tidbits/tidbits")
+	logger.Debug("still disabled")
+	logger.Info("enabled")
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"enabled"}`, sink.Get())
+
+	assert.Panics(t, func() { PinpointLevelSource(nil) })
+}
+
+func TestLevelSourceWithExplicitLevel(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelDebug)
+	root := slog.New(NewSlogConvenience(SlogOptions{
+		LogLevel:    slog.LevelInfo,
+		LevelSource: ContextLevelSource(),
+	}, sink.Handler()))
+
+	ctx := WithContextLevel(context.Background(), slog.LevelDebug)
+	root.With(WithLogLevel(slog.LevelError)).DebugContext(ctx, "suppressed")
+	root.DebugContext(ctx, "enabled")
+
+	assert.Equal(t, `{"time":"","level":"DEBUG","msg":"enabled"}`, sink.Get())
+}
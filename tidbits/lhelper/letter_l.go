@@ -3,6 +3,7 @@ package lhelper
 import (
 	"context"
 	"fmt"
+	"github.com/Cyberax/slog-tidbits/tidbits"
 	"log/slog"
 	"runtime"
 	"sync/atomic"
@@ -32,6 +33,12 @@ func WithDerivedLogger(ctx context.Context, attrs ...any) context.Context {
 	return WithLogger(ctx, logger)
 }
 
+// Named puts the derived logger with the given name into the context. The name is hierarchical
+// ("db.pool"), it's added as the "logger" attribute, and its level is set by SlogOptions.NamedLevels.
+func Named(ctx context.Context, name string) context.Context {
+	return WithDerivedLogger(ctx, tidbits.WithLoggerName(name))
+}
+
 func DeriveLogger(ctx context.Context, attrs ...any) *slog.Logger {
 	logger, ok := ctx.Value(loggerKey).(*slog.Logger)
 	if ok {
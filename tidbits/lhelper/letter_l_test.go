@@ -66,3 +66,23 @@ func TestExtractors(t *testing.T) {
 	L(ctx).Info("hello, world")
 	assert.Equal(t, `{"time":"","level":"INFO","msg":"hello, world","TestValue":"through_context"}`, sink.Get())
 }
+
+func TestNamed(t *testing.T) {
+	lvls := tidbits.NewNamedLogLevels().WithOverride(slog.LevelError, "db")
+	sink := tidbits.NewSinkingLogger(slog.LevelDebug)
+	conv := tidbits.NewSlogConvenience(tidbits.SlogOptions{NamedLevels: lvls}, sink.Handler())
+
+	ctx := Named(WithLogger(context.Background(), slog.New(conv)), "db.pool")
+	L(ctx).Warn("suppressed")
+	L(ctx).Error("failed")
+	assert.Equal(t, `{"time":"","level":"ERROR","msg":"failed","logger":"db.pool"}`, sink.Get())
+}
+
+func TestCallSiteControls(t *testing.T) {
+	sink := tidbits.NewSinkingLogger(slog.LevelInfo)
+	conv := tidbits.NewSlogConvenience(tidbits.SlogOptions{}, sink.Handler())
+
+	ctx := WithLogger(context.Background(), slog.New(conv))
+	L(ctx).Info("bumped", tidbits.WithLogLevel(slog.LevelError))
+	assert.Equal(t, `{"time":"","level":"ERROR","msg":"bumped"}`, sink.Get())
+}
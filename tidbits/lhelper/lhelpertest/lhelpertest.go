@@ -0,0 +1,15 @@
+// Package lhelpertest contains the lhelper functions for tests, it's separate from lhelper, so
+// the production binaries don't import the testing package.
+package lhelpertest
+
+import (
+	"context"
+	"github.com/Cyberax/slog-tidbits/tidbits/lhelper"
+	"github.com/Cyberax/slog-tidbits/tidbits/tidbitstest"
+	"testing"
+)
+
+// WithTestLogger puts the logger that writes to the test output into the context
+func WithTestLogger(ctx context.Context, t testing.TB, opts tidbitstest.TestLoggerOptions) context.Context {
+	return lhelper.WithLogger(ctx, tidbitstest.NewTestLogger(t, opts).Logger)
+}
@@ -0,0 +1,35 @@
+package lhelpertest
+
+import (
+	"bytes"
+	"context"
+	"github.com/Cyberax/slog-tidbits/tidbits/lhelper"
+	"github.com/Cyberax/slog-tidbits/tidbits/tidbitstest"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"regexp"
+	"testing"
+)
+
+// capturingTB captures the test output
+type capturingTB struct {
+	testing.TB
+	out bytes.Buffer
+}
+
+func (c *capturingTB) Helper()               {}
+func (c *capturingTB) Cleanup(func())        {}
+func (c *capturingTB) Output() io.Writer     { return &c.out }
+func (c *capturingTB) Log(args ...any)       { panic("the output must go to Output()") }
+func (c *capturingTB) Errorf(string, ...any) {}
+
+func TestWithTestLogger(t *testing.T) {
+	tb := &capturingTB{}
+	ctx := WithTestLogger(context.Background(), tb, tidbitstest.TestLoggerOptions{Level: slog.LevelInfo})
+	lhelper.L(ctx).Debug("skipped")
+	lhelper.L(ctx).Info("hello, test", slog.Int("key", 42))
+
+	out := regexp.MustCompile(`^\d\d:\d\d:\d\d\.\d+ +`).ReplaceAllString(tb.out.String(), "")
+	assert.Equal(t, "INFO  lhelpertest_test.go:31  hello, test  key=42  \n", out)
+}
@@ -0,0 +1,152 @@
+package tidbits
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"sync"
+)
+
+// LoggerAttrName is the attribute with the logger name, the records are counted per logger
+const LoggerAttrName = "logger"
+
+// RecordMetric describes the counted record
+type RecordMetric struct {
+	Level slog.Level
+	// Logger is the value of the top-level "logger" attribute, empty if there's none
+	Logger string
+	// CallSite is the function that has logged the record, empty if the record has no PC
+	CallSite string
+}
+
+// LogMetrics receives the record counts, the implementations must be safe for concurrent use
+type LogMetrics interface {
+	CountRecord(ctx context.Context, m RecordMetric)
+}
+
+// MetricsHandler counts the records accepted by the delegate handler (the records with the
+// Handle errors are not counted). SlogConvenience drops the records filtered by the pinpoint levels,
+// the sampler and the stack deduplication without reporting it, so the MetricsHandler must be
+// placed below SlogConvenience to count only the emitted records:
+//
+//	slog.New(NewSlogConvenience(opts, NewMetricsHandler(sink, metrics)))
+type MetricsHandler struct {
+	delegate slog.Handler
+	metrics  LogMetrics
+
+	logger  string
+	inGroup bool
+}
+
+var _ slog.Handler = &MetricsHandler{}
+
+func NewMetricsHandler(delegate slog.Handler, metrics LogMetrics) *MetricsHandler {
+	return &MetricsHandler{delegate: delegate, metrics: metrics}
+}
+
+func (m *MetricsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return m.delegate.Enabled(ctx, level)
+}
+
+func (m *MetricsHandler) Handle(ctx context.Context, record slog.Record) error {
+	metric := RecordMetric{Level: record.Level, Logger: m.logger}
+	if !m.inGroup {
+		record.Attrs(func(a slog.Attr) bool {
+			if a.Key == LoggerAttrName {
+				metric.Logger = a.Value.Resolve().String()
+			}
+			return true
+		})
+	}
+	if record.PC != 0 {
+		metric.CallSite = cachedFuncNameForPC(record.PC)
+	}
+
+	err := m.delegate.Handle(ctx, record)
+	if err != nil {
+		return err
+	}
+	m.metrics.CountRecord(ctx, metric)
+	return nil
+}
+
+func (m *MetricsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	res := *m
+	res.delegate = m.delegate.WithAttrs(attrs)
+	if !m.inGroup {
+		for _, a := range attrs {
+			if a.Key == LoggerAttrName {
+				res.logger = a.Value.Resolve().String()
+			}
+		}
+	}
+	return &res
+}
+
+func (m *MetricsHandler) WithGroup(name string) slog.Handler {
+	res := *m
+	res.delegate = m.delegate.WithGroup(name)
+	res.inGroup = res.inGroup || name != ""
+	return &res
+}
+
+// ExpvarMetrics counts the records in the expvar maps, the logger and call site counters are
+// keyed by "<name>:<LEVEL>" (e.g. "db.pool:ERROR")
+type ExpvarMetrics struct {
+	ByLevel    *expvar.Map
+	ByLogger   *expvar.Map
+	ByCallSite *expvar.Map
+}
+
+var _ LogMetrics = &ExpvarMetrics{}
+
+var expvarMtx sync.Mutex
+
+// NewExpvarMetrics creates the counters and publishes them as the "level", "logger" and "call_site"
+// submaps of the expvar map with the given name. The counters are not published if the name is empty.
+// If the map with the name has already been published by NewExpvarMetrics, its counters are shared.
+// It panics if the name is taken by another expvar variable.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	res := &ExpvarMetrics{
+		ByLevel:    new(expvar.Map).Init(),
+		ByLogger:   new(expvar.Map).Init(),
+		ByCallSite: new(expvar.Map).Init(),
+	}
+	if name == "" {
+		return res
+	}
+
+	expvarMtx.Lock()
+	defer expvarMtx.Unlock()
+
+	existing := expvar.Get(name)
+	if existing == nil {
+		root := expvar.NewMap(name)
+		root.Set("level", res.ByLevel)
+		root.Set("logger", res.ByLogger)
+		root.Set("call_site", res.ByCallSite)
+		return res
+	}
+
+	root, ok := existing.(*expvar.Map)
+	if ok {
+		res.ByLevel, _ = root.Get("level").(*expvar.Map)
+		res.ByLogger, _ = root.Get("logger").(*expvar.Map)
+		res.ByCallSite, _ = root.Get("call_site").(*expvar.Map)
+	}
+	if !ok || res.ByLevel == nil || res.ByLogger == nil || res.ByCallSite == nil {
+		panic("expvar variable " + name + " is not published by NewExpvarMetrics")
+	}
+	return res
+}
+
+func (e *ExpvarMetrics) CountRecord(ctx context.Context, m RecordMetric) {
+	lvl := m.Level.String()
+	e.ByLevel.Add(lvl, 1)
+	if m.Logger != "" {
+		e.ByLogger.Add(m.Logger+":"+lvl, 1)
+	}
+	if m.CallSite != "" {
+		e.ByCallSite.Add(m.CallSite+":"+lvl, 1)
+	}
+}
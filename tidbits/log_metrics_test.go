@@ -0,0 +1,73 @@
+package tidbits
+
+import (
+	"context"
+	"expvar"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	t.Parallel()
+
+	metrics := NewExpvarMetrics("")
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewMetricsHandler(sink.Handler(), metrics))
+
+	logger.Debug("disabled")
+	logger.Info("info")
+	dbLogger := logger.With(slog.String(LoggerAttrName, "db.pool"))
+	dbLogger.Error("failed")
+	dbLogger.Error("failed again")
+	logger.Warn("warning", slog.String(LoggerAttrName, "http"))
+	// The logger attribute inside a group doesn't name the logger
+	logger.WithGroup("req").Warn("grouped", slog.String(LoggerAttrName, "nested"))
+
+	assert.Equal(t, `{"ERROR": 2, "INFO": 1, "WARN": 2}`, metrics.ByLevel.String())
+	assert.Equal(t, `{"db.pool:ERROR": 2, "http:WARN": 1}`, metrics.ByLogger.String())
+	assert.Equal(t, int64(2), metrics.ByCallSite.Get(
+		"github.com/Cyberax/slog-tidbits/tidbits.TestMetricsHandler:ERROR").(*expvar.Int).Value())
+
+	// The records are passed to the delegate
+	assert.Contains(t, sink.Get(), `"msg":"failed again","logger":"db.pool"`)
+}
+
+func TestMetricsBelowConvenience(t *testing.T) {
+	t.Parallel()
+
+	metrics := NewExpvarMetrics("")
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{
+		Sampler: NewEveryNthSampler(2, slog.LevelWarn),
+	}, NewMetricsHandler(sink.Handler(), metrics)))
+
+	for i := 0; i < 4; i++ {
+		logger.Warn("sampled")
+	}
+	logger.With(WithLoggerName("db")).Error("failed")
+
+	assert.Equal(t, `{"ERROR": 1, "WARN": 2}`, metrics.ByLevel.String())
+	assert.Equal(t, `{"db:ERROR": 1}`, metrics.ByLogger.String())
+}
+
+func TestExpvarMetricsPublished(t *testing.T) {
+	t.Parallel()
+
+	metrics := NewExpvarMetrics("test_log_records")
+	metrics.ByLevel.Init()
+	metrics.ByLogger.Init()
+	metrics.CountRecord(context.Background(), RecordMetric{Level: slog.LevelWarn, Logger: "db"})
+
+	assert.Equal(t, `{"call_site": {}, "level": {"WARN": 1}, "logger": {"db:WARN": 1}}`,
+		expvar.Get("test_log_records").String())
+
+	// The counters are shared with the already published metrics
+	again := NewExpvarMetrics("test_log_records")
+	assert.Same(t, metrics.ByLevel, again.ByLevel)
+
+	if expvar.Get("test_log_int") == nil {
+		expvar.NewInt("test_log_int")
+	}
+	assert.Panics(t, func() { NewExpvarMetrics("test_log_int") })
+}
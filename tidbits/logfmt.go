@@ -0,0 +1,213 @@
+package tidbits
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type LogfmtOptions struct {
+	Level     slog.Leveler
+	AddSource bool
+	// NumberedStack renders the StackValue as the numbered keys (stack.0="panic msg" stack.1="file:line fn"),
+	// instead of a single escaped multi-line value
+	NumberedStack bool
+}
+
+// LogfmtHandler writes the records in the logfmt format: `key=value key2="quoted value"`. The groups
+// are flattened into the dotted keys.
+type LogfmtHandler struct {
+	opts LogfmtOptions
+
+	mtx *sync.Mutex
+	out io.Writer
+
+	prefix    string // The group prefix for the attribute keys ("group1.group2.")
+	preformed []byte // The attributes from WithAttrs, already formatted
+}
+
+var _ slog.Handler = &LogfmtHandler{}
+
+func NewLogfmtHandler(out io.Writer, opts *LogfmtOptions) *LogfmtHandler {
+	h := &LogfmtHandler{
+		mtx: &sync.Mutex{},
+		out: out,
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelInfo
+	}
+	return h
+}
+
+func (h *LogfmtHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+func (h *LogfmtHandler) Handle(ctx context.Context, record slog.Record) error {
+	buf := bytes.NewBuffer(make([]byte, 0, 256))
+
+	if !record.Time.IsZero() {
+		h.appendPair(buf, slog.TimeKey, record.Time.Format(time.RFC3339Nano))
+	}
+	h.appendPair(buf, slog.LevelKey, record.Level.String())
+	if h.opts.AddSource && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		h.appendPair(buf, slog.SourceKey, frame.File+":"+strconv.Itoa(frame.Line))
+	}
+	h.appendPair(buf, slog.MessageKey, record.Message)
+
+	buf.Write(h.preformed)
+	record.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(buf, h.prefix, a)
+		return true
+	})
+	buf.WriteByte('\n')
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	_, err := h.out.Write(buf.Bytes()[1:])
+	return err
+}
+
+func (h *LogfmtHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	buf := bytes.NewBuffer(slices.Clone(h.preformed))
+	for _, a := range attrs {
+		h.appendAttr(buf, h.prefix, a)
+	}
+
+	res := *h
+	res.preformed = buf.Bytes()
+	return &res
+}
+
+func (h *LogfmtHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	res := *h
+	res.prefix = h.prefix + name + "."
+	return &res
+}
+
+func (h *LogfmtHandler) appendAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	if sv, ok := a.Value.Any().(*StackValue); ok && a.Value.Kind() == slog.KindLogValuer {
+		h.appendStack(buf, prefix+a.Key, sv)
+		return
+	}
+
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			h.appendAttr(buf, groupPrefix, ga)
+		}
+		return
+	}
+
+	h.appendPair(buf, prefix+a.Key, formatLogfmtValue(a.Value))
+}
+
+func (h *LogfmtHandler) appendStack(buf *bytes.Buffer, key string, sv *StackValue) {
+	if !h.opts.NumberedStack {
+		text, _ := sv.MarshalText()
+		h.appendPair(buf, key, strings.TrimSuffix(string(text), "\n"))
+		return
+	}
+
+	for i, e := range sv.JSONStack() {
+		val := e.Msg
+		if i != 0 {
+			val = e.String()
+		}
+		h.appendPair(buf, key+"."+strconv.Itoa(i), val)
+	}
+}
+
+func (h *LogfmtHandler) appendPair(buf *bytes.Buffer, key, val string) {
+	// The leading space of the first pair is removed in Handle
+	buf.WriteByte(' ')
+	buf.WriteString(sanitizeLogfmtKey(key))
+	buf.WriteByte('=')
+	if needsLogfmtQuoting(val) {
+		buf.WriteString(strconv.Quote(val))
+	} else {
+		buf.WriteString(val)
+	}
+}
+
+func formatLogfmtValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		switch av := v.Any().(type) {
+		case error:
+			return av.Error()
+		case encoding.TextMarshaler:
+			text, err := av.MarshalText()
+			if err != nil {
+				return "!ERROR:" + err.Error()
+			}
+			return string(text)
+		case []byte:
+			return string(av)
+		default:
+			return fmt.Sprintf("%+v", av)
+		}
+	default:
+		// Numbers, bools and durations
+		return v.String()
+	}
+}
+
+// The keys can't contain spaces, equal signs, quotes or control characters
+func sanitizeLogfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func needsLogfmtQuoting(val string) bool {
+	if val == "" {
+		return true
+	}
+	for _, r := range val {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || unicode.IsControl(r) ||
+			unicode.IsSpace(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package tidbits
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var logfmtTimeRe = regexp.MustCompile(`(?m)^time=\S+ `)
+
+func TestLogfmtHandler(t *testing.T) {
+	t.Parallel()
+
+	data := &bytes.Buffer{}
+	logger := slog.New(NewLogfmtHandler(data, &LogfmtOptions{Level: slog.LevelDebug}))
+
+	logger.Debug("hello, world", slog.Int("count", 42), slog.String("empty", ""),
+		slog.String("quoted", `say "hi"`), slog.String("multi", "line1\nline2"),
+		slog.String("eq", "a=b"), slog.Bool("ok", true), slog.Duration("took", 1500*time.Millisecond),
+		slog.Any("err", errors.New("failed badly")), slog.String("bad key", "x"))
+
+	logger.With(slog.String("component", "db")).WithGroup("req").
+		With(slog.Int("id", 1)).Info("grouped", slog.Group("query", slog.String("table", "users")),
+		slog.Group("", slog.String("inlined", "yes")), slog.Group("empty"))
+
+	assert.Equal(t, `level=DEBUG msg="hello, world" count=42 empty="" quoted="say \"hi\"" `+
+		`multi="line1\nline2" eq="a=b" ok=true took=1.5s err="failed badly" bad_key=x
+level=INFO msg=grouped component=db req.id=1 req.query.table=users req.inlined=yes
+`, logfmtTimeRe.ReplaceAllString(data.String(), ""))
+}
+
+func TestLogfmtStacks(t *testing.T) {
+	t.Parallel()
+
+	data := &bytes.Buffer{}
+	conv := slog.New(NewSlogConvenience(SlogOptions{}, NewLogfmtHandler(data, nil)))
+	opts := StackOptions{SkipTesting: true}
+	conv.Error("failed", StackTraceAttrWithOptions(opts, "boom"), slog.Int("key", 1))
+
+	numbered := &bytes.Buffer{}
+	conv = slog.New(NewSlogConvenience(SlogOptions{},
+		NewLogfmtHandler(numbered, &LogfmtOptions{NumberedStack: true})))
+	conv.Error("failed", StackTraceAttrWithOptions(opts, "boom"))
+
+	assert.Equal(t, `level=ERROR msg=failed key=1 stack="boom\n`+
+		`github.com/Cyberax/slog-tidbits/tidbits/stacks.go:61 StackTraceAttrWithOptions\n`+
+		`github.com/Cyberax/slog-tidbits/tidbits/logfmt_test.go:43 TestLogfmtStacks"`,
+		strings.TrimSpace(logfmtTimeRe.ReplaceAllString(data.String(), "")))
+	assert.Equal(t, `level=ERROR msg=failed stack.0=boom `+
+		`stack.1="github.com/Cyberax/slog-tidbits/tidbits/stacks.go:61 StackTraceAttrWithOptions" `+
+		`stack.2="github.com/Cyberax/slog-tidbits/tidbits/logfmt_test.go:48 TestLogfmtStacks"`,
+		strings.TrimSpace(logfmtTimeRe.ReplaceAllString(numbered.String(), "")))
+}
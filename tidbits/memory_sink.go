@@ -5,15 +5,19 @@ import (
 	"context"
 	"log/slog"
 	"strings"
+	"sync"
 )
 
 // SinkingLogger This is a logger that writes messages to a memory buffer, it's intended to be
 // used in unit tests to make sure that the log messages are expected. As a convenience feature
 // to make it easier to match the messages, this logger also removes the timestamp from the log
 // entries.
+//
+// The logger is safe for concurrent use. The messages logged with a context from
+// WithSinkPartition are stored separately, so parallel tests can share the logger.
 type SinkingLogger struct {
 	*slog.Logger
-	data *bytes.Buffer
+	data *partitionedBuffer
 }
 
 func NewSinkingLogger(lvl slog.Level) *SinkingLogger {
@@ -21,7 +25,7 @@ func NewSinkingLogger(lvl slog.Level) *SinkingLogger {
 }
 
 func NewJsonOrTextSinkingLogger(lvl slog.Level, textMode bool) *SinkingLogger {
-	data := &bytes.Buffer{}
+	data := &partitionedBuffer{partitions: make(map[string]*bytes.Buffer)}
 	opts := &slog.HandlerOptions{
 		Level:     lvl,
 		AddSource: false,
@@ -42,40 +46,161 @@ func NewJsonOrTextSinkingLogger(lvl slog.Level, textMode bool) *SinkingLogger {
 	}
 
 	return &SinkingLogger{
-		Logger: slog.New(lh),
+		Logger: slog.New(&partitionHandler{delegate: lh, data: data}),
 		data:   data,
 	}
 }
 
 // Get returns the accumulated log data and resets the buffer
 func (s *SinkingLogger) Get() string {
-	res := s.data.String()
-	s.data.Reset()
-	return strings.TrimSpace(res)
+	return s.GetPartition("")
+}
+
+// GetPartition returns the accumulated log data for the partition and resets it
+func (s *SinkingLogger) GetPartition(name string) string {
+	return strings.TrimSpace(s.data.getAndReset(name))
+}
+
+type sinkPartitionKey struct{}
+
+// WithSinkPartition returns the context that routes the messages logged with it
+// into a separate partition of the SinkingLogger
+func WithSinkPartition(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, sinkPartitionKey{}, name)
+}
+
+// partitionedBuffer stores the data written by the delegate handler into the partition
+// of the record that is currently being handled
+type partitionedBuffer struct {
+	mtx        sync.Mutex
+	current    string
+	partitions map[string]*bytes.Buffer
+}
+
+// Write is called only while the mutex is held by partitionHandler.Handle
+func (p *partitionedBuffer) Write(data []byte) (int, error) {
+	buf, ok := p.partitions[p.current]
+	if !ok {
+		buf = &bytes.Buffer{}
+		p.partitions[p.current] = buf
+	}
+	return buf.Write(data)
+}
+
+func (p *partitionedBuffer) getAndReset(name string) string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	buf, ok := p.partitions[name]
+	if !ok {
+		return ""
+	}
+	delete(p.partitions, name)
+	return buf.String()
+}
+
+type partitionHandler struct {
+	delegate slog.Handler
+	data     *partitionedBuffer
+}
+
+var _ slog.Handler = &partitionHandler{}
+
+func (p *partitionHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return p.delegate.Enabled(ctx, level)
+}
+
+func (p *partitionHandler) Handle(ctx context.Context, record slog.Record) error {
+	partition, _ := ctx.Value(sinkPartitionKey{}).(string)
+
+	p.data.mtx.Lock()
+	defer p.data.mtx.Unlock()
+	p.data.current = partition
+	return p.delegate.Handle(ctx, record)
+}
+
+func (p *partitionHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &partitionHandler{delegate: p.delegate.WithAttrs(attrs), data: p.data}
+}
+
+func (p *partitionHandler) WithGroup(name string) slog.Handler {
+	return &partitionHandler{delegate: p.delegate.WithGroup(name), data: p.data}
 }
 
 // NopLogger This is a logger that does nothing, it's intended to be used in unit tests to
-// suppress log messages.
+// suppress log messages. The levels at or above lvl are reported as enabled, so the code guarded
+// by the Enabled checks still runs, but the records are discarded. The attributes are not
+// resolved, so the LogValuers are never called.
+//
+// In the counting mode, the logger also counts the records that would have been emitted, so the
+// tests can cheaply check that no warnings were logged.
 type NopLogger struct {
 	*slog.Logger
+	counts *levelCounts
 }
 
 func NewNopLogger(lvl slog.Level) *NopLogger {
 	return &NopLogger{
-		Logger: slog.New(&nopHandler{}),
+		Logger: slog.New(&nopHandler{level: lvl}),
 	}
 }
 
+func NewCountingNopLogger(lvl slog.Level) *NopLogger {
+	counts := &levelCounts{counts: make(map[slog.Level]int64)}
+	return &NopLogger{
+		Logger: slog.New(&nopHandler{level: lvl, counts: counts}),
+		counts: counts,
+	}
+}
+
+// Count returns the number of records with exactly the specified level, it's always 0 if the
+// logger is not in the counting mode
+func (n *NopLogger) Count(lvl slog.Level) int64 {
+	if n.counts == nil {
+		return 0
+	}
+	n.counts.mtx.Lock()
+	defer n.counts.mtx.Unlock()
+	return n.counts.counts[lvl]
+}
+
+// CountAtLeast returns the number of records with the level greater or equal to the specified one
+func (n *NopLogger) CountAtLeast(lvl slog.Level) int64 {
+	if n.counts == nil {
+		return 0
+	}
+	n.counts.mtx.Lock()
+	defer n.counts.mtx.Unlock()
+	var res int64
+	for l, c := range n.counts.counts {
+		if l >= lvl {
+			res += c
+		}
+	}
+	return res
+}
+
+type levelCounts struct {
+	mtx    sync.Mutex
+	counts map[slog.Level]int64
+}
+
 type nopHandler struct {
+	level  slog.Level
+	counts *levelCounts
 }
 
 var _ slog.Handler = &nopHandler{}
 
 func (n *nopHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return false
+	return level >= n.level
 }
 
 func (n *nopHandler) Handle(ctx context.Context, record slog.Record) error {
+	if n.counts != nil {
+		n.counts.mtx.Lock()
+		defer n.counts.mtx.Unlock()
+		n.counts.counts[record.Level]++
+	}
 	return nil
 }
 
@@ -2,9 +2,13 @@ package tidbits
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"log"
 	"log/slog"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -30,3 +34,54 @@ func TestNop(t *testing.T) {
 	sink.Error("VERY BAD!")
 	assert.True(t, out.Len() == 0)
 }
+
+func TestNopLevels(t *testing.T) {
+	t.Parallel()
+
+	nop := NewNopLogger(slog.LevelWarn)
+	assert.False(t, nop.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, nop.Enabled(context.Background(), slog.LevelWarn))
+	nop.Warn("not counted")
+	assert.Equal(t, int64(0), nop.Count(slog.LevelWarn))
+
+	counting := NewCountingNopLogger(slog.LevelInfo)
+	assert.False(t, counting.Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, counting.Enabled(context.Background(), slog.LevelInfo))
+	counting.Debug("skipped")
+	counting.Info("info")
+	counting.With("key", "val").Warn("warning")
+	counting.Error("error1")
+	counting.Error("error2")
+
+	assert.Equal(t, int64(0), counting.Count(slog.LevelDebug))
+	assert.Equal(t, int64(1), counting.Count(slog.LevelInfo))
+	assert.Equal(t, int64(2), counting.Count(slog.LevelError))
+	assert.Equal(t, int64(3), counting.CountAtLeast(slog.LevelWarn))
+}
+
+func TestSinkingLogConcurrency(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := WithSinkPartition(context.Background(), fmt.Sprintf("part%d", i))
+			for j := 0; j < 100; j++ {
+				sink.InfoContext(ctx, "partitioned", slog.Int("i", i))
+				sink.Info("shared")
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1000, strings.Count(sink.Get(), "shared"))
+	for i := 0; i < 10; i++ {
+		data := sink.GetPartition(fmt.Sprintf("part%d", i))
+		assert.Equal(t, 100, strings.Count(data, fmt.Sprintf(`"msg":"partitioned","i":%d}`, i)))
+	}
+	assert.Empty(t, sink.GetPartition("part0"))
+}
@@ -0,0 +1,117 @@
+package tidbits
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// TIDBITS_NAMED_ENV_PREFIX is the prefix for the named logger levels: TIDBITS_LOG_NAMED_WARN=db,http
+const TIDBITS_NAMED_ENV_PREFIX = TIDBITS_ENV_PREFIX + "NAMED_"
+
+// NamedLogLevels is the registry of levels for the named loggers. The names are hierarchical, with
+// the dot as the separator: the level for "db" applies to "db.pool" unless "db.pool" has its own level.
+// The lookups are lock-free, the overrides replace the whole snapshot of the levels.
+type NamedLogLevels struct {
+	mtx      sync.Mutex
+	snapshot atomic.Pointer[namedLevelsSnapshot]
+}
+
+// The immutable set of the levels, with the cache of the lookups
+type namedLevelsSnapshot struct {
+	levels     map[string]slog.Level
+	levelCache sync.Map // string -> namedLevel
+}
+
+type namedLevel struct {
+	level slog.Level
+	found bool
+}
+
+func NewNamedLogLevels() *NamedLogLevels {
+	res := &NamedLogLevels{}
+	res.snapshot.Store(&namedLevelsSnapshot{levels: make(map[string]slog.Level)})
+	return res
+}
+
+func (n *NamedLogLevels) WithOverride(l slog.Level, names ...string) *NamedLogLevels {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	levels := maps.Clone(n.snapshot.Load().levels)
+	for _, name := range names {
+		levels[name] = l
+	}
+	n.snapshot.Store(&namedLevelsSnapshot{levels: levels})
+	return n
+}
+
+// WithSpec parses the comma-separated list of the levels: "db=WARN,db.pool=DEBUG"
+func (n *NamedLogLevels) WithSpec(spec string) (*NamedLogLevels, error) {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, levelName, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("no level for the logger name: %s", entry)
+		}
+		var lvl slog.Level
+		err := (&lvl).UnmarshalText([]byte(strings.TrimSpace(levelName)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the level for %s: %w", name, err)
+		}
+		n.WithOverride(lvl, strings.TrimSpace(name))
+	}
+	return n, nil
+}
+
+func (n *NamedLogLevels) WithEnvironmentOverrides() *NamedLogLevels {
+	return n.WithEnvironmentListOverrides(os.Environ())
+}
+
+func (n *NamedLogLevels) WithEnvironmentListOverrides(env []string) *NamedLogLevels {
+	for _, e := range env {
+		lvl, names, ok := parseEnvOverride(e, TIDBITS_NAMED_ENV_PREFIX)
+		if ok {
+			n.WithOverride(lvl, names...)
+		}
+	}
+	return n
+}
+
+// LevelForName finds the level for the logger name or its closest parent
+func (n *NamedLogLevels) LevelForName(name string) (slog.Level, bool) {
+	snap := n.snapshot.Load()
+	cached, ok := snap.levelCache.Load(name)
+	if ok {
+		res := cached.(namedLevel)
+		return res.level, res.found
+	}
+
+	var res namedLevel
+	for cur := name; ; {
+		res.level, res.found = snap.levels[cur]
+		if res.found {
+			break
+		}
+		idx := strings.LastIndexByte(cur, '.')
+		if idx == -1 {
+			break
+		}
+		cur = cur[:idx]
+	}
+
+	snap.levelCache.Store(name, res)
+	return res.level, res.found
+}
+
+// Levels returns the copy of the configured levels
+func (n *NamedLogLevels) Levels() map[string]slog.Level {
+	return maps.Clone(n.snapshot.Load().levels)
+}
@@ -0,0 +1,92 @@
+package tidbits
+
+import (
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+func TestNamedLevelsHierarchy(t *testing.T) {
+	t.Parallel()
+
+	lvls, err := NewNamedLogLevels().WithSpec("db=WARN, db.pool=DEBUG,http=ERROR-2")
+	assert.NoError(t, err)
+
+	for name, expected := range map[string]slog.Level{
+		"db": slog.LevelWarn, "db.query": slog.LevelWarn, "db.pool": slog.LevelDebug,
+		"db.pool.conn": slog.LevelDebug, "http": slog.LevelError - 2,
+	} {
+		lvl, ok := lvls.LevelForName(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, expected, lvl, name)
+	}
+
+	// Only the whole name segments match
+	_, ok := lvls.LevelForName("dbx")
+	assert.False(t, ok)
+	_, ok = lvls.LevelForName("")
+	assert.False(t, ok)
+
+	_, err = NewNamedLogLevels().WithSpec("db")
+	assert.Error(t, err)
+	_, err = NewNamedLogLevels().WithSpec("db=LOUD")
+	assert.Error(t, err)
+}
+
+func TestNamedLevelsEnvironment(t *testing.T) {
+	t.Parallel()
+
+	env := []string{
+		"TIDBITS_LOG_NAMED_WARN=db,http",
+		"TIDBITS_LOG_NAMED_DEBUG_MINUS_4=db.pool",
+		"TIDBITS_LOG_ERROR=github.com/package1",
+	}
+	lvls := NewNamedLogLevels().WithEnvironmentListOverrides(env)
+	assert.Equal(t, map[string]slog.Level{"db": slog.LevelWarn, "http": slog.LevelWarn,
+		"db.pool": slog.LevelDebug - 4}, lvls.Levels())
+
+	// The pinpoint levels ignore the named levels
+	pinpoint := NewPinpointLogLevels().WithEnvironmentListOverrides(env)
+	_, ok := pinpoint.LevelForLocation("db")
+	assert.False(t, ok)
+	lvl, ok := pinpoint.LevelForLocation("github.com/package1.Func")
+	assert.True(t, ok)
+	assert.Equal(t, slog.LevelError, lvl)
+}
+
+func TestNamedLoggers(t *testing.T) {
+	t.Parallel()
+
+	lvls, err := NewNamedLogLevels().WithSpec("db=WARN,db.pool=DEBUG")
+	assert.NoError(t, err)
+
+	sink := NewSinkingLogger(slog.LevelDebug)
+	conv := slog.New(NewSlogConvenience(SlogOptions{LogLevel: slog.LevelInfo, NamedLevels: lvls}, sink.Handler()))
+
+	db := conv.With(WithLoggerName("db"))
+	db.Info("db info is suppressed")
+	db.Warn("db warning")
+
+	pool := db.With(WithLoggerName("db.pool"), slog.Int("size", 10))
+	pool.Debug("pool debug")
+
+	conv.With(WithLoggerName("other")).Debug("other debug is suppressed")
+
+	assert.Equal(t, `{"time":"","level":"WARN","msg":"db warning","logger":"db"}
+{"time":"","level":"DEBUG","msg":"pool debug","size":10,"logger":"db.pool"}`, sink.Get())
+}
+
+func TestNamedLevelsExplicitOverride(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelDebug)
+	root := slog.New(NewSlogConvenience(SlogOptions{
+		NamedLevels: NewNamedLogLevels().WithOverride(slog.LevelWarn, "db"),
+	}, sink.Handler()))
+
+	db := root.With(WithLoggerName("db"))
+	db.Info("suppressed")
+	db.With(WithLogLevel(slog.LevelDebug)).Debug("explicit")
+
+	assert.Equal(t, `{"time":"","level":"DEBUG","msg":"explicit","logger":"db"}`, sink.Get())
+}
@@ -0,0 +1,80 @@
+package tidbits
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+)
+
+var ErrNoPanic = errors.New("no panic found in the text")
+
+// ParsePanicText parses the standard Go traceback text (e.g. the stderr of a crashed child process)
+// into a StackValue. The panic message and the stack of the panicking goroutine are preserved,
+// so the result is logged exactly like a locally captured stack. The filtering options are applied
+// to the parsed frames, the runtime frames are present only if the process ran with GOTRACEBACK=system.
+//
+// Example:
+//
+//	panic: runtime error: invalid memory address or nil pointer dereference
+//	[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x47db15]
+//
+//	goroutine 1 [running]:
+//	main.main()
+//		/tmp/pp/main.go:3 +0x15
+func ParsePanicText(text []byte, opts StackOptions) (*StackValue, error) {
+	msg, ok := findPanicMessage(text)
+	if !ok {
+		return nil, ErrNoPanic
+	}
+
+	// The traceback parser skips everything before the first goroutine header
+	goroutines := parseTraceback(text)
+
+	// The panicking goroutine is printed first, the truncated crash logs might have no goroutines
+	res := &StackValue{opts: opts, msg: msg}
+	if len(goroutines) != 0 {
+		res.parsed = goroutines[0].frames
+	}
+	if res.parsed == nil {
+		res.parsed = []symbolFrame{}
+	}
+	return res, nil
+}
+
+// Find the panic message, it can span several lines for the nested panics:
+//
+//	panic: first [recovered]
+//		panic: second
+//
+// The "panic: " and "fatal error: " prefixes are stripped. The message ends with a blank line,
+// a goroutine header or the end of the text (for the truncated crash logs).
+func findPanicMessage(text []byte) (string, bool) {
+	var msgLines []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if msgLines == nil {
+			if msg, ok := strings.CutPrefix(line, "panic: "); ok {
+				msgLines = append(msgLines, msg)
+			} else if msg, ok := strings.CutPrefix(line, "fatal error: "); ok {
+				msgLines = append(msgLines, msg)
+			}
+			continue
+		}
+
+		if line == "" || goroutineHeaderRe.MatchString(line) {
+			// The message is over
+			return strings.Join(msgLines, " "), true
+		}
+		msgLines = append(msgLines, line)
+	}
+
+	if msgLines != nil {
+		return strings.Join(msgLines, " "), true
+	}
+	return "", false
+}
@@ -0,0 +1,96 @@
+package tidbits
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+const samplePanic = `some unrelated output
+panic: first [recovered]
+	panic: second
+
+goroutine 1 [running]:
+main.(*T).boom.func1()
+	/tmp/pp/main.go:3 +0x25
+panic({0x5185a8?, 0x485f38?})
+	/usr/local/go/src/runtime/panic.go:859 +0x125
+main.(*T).boom(0x9652ff01e0?)
+	/tmp/pp/main.go:3 +0x3e
+main.main()
+	/tmp/pp/main.go:4 +0x18
+
+goroutine 5 [chan receive]:
+main.worker()
+	/tmp/pp/main.go:10 +0x18
+exit status 2
+`
+
+func TestParsePanicText(t *testing.T) {
+	t.Parallel()
+
+	sv, err := ParsePanicText([]byte(samplePanic), StackOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []StackElement{
+		{Msg: "first [recovered] panic: second"},
+		{Fl: "main.(*T).boom/main.go:3", Fn: "func1"},
+		{Fl: "runtime/panic.go:859", Fn: "gopanic"},
+		{Fl: "main.(*T)/main.go:3", Fn: "boom"},
+		{Fl: "main/main.go:4", Fn: "main"},
+	}, sv.JSONStack())
+
+	// Skip the deferred function
+	sv, err = ParsePanicText([]byte(samplePanic), StackOptions{SkipToFirstPanic: true})
+	assert.NoError(t, err)
+	text, err := sv.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, `first [recovered] panic: second
+main.(*T)/main.go:3 boom
+main/main.go:4 main
+`, string(text))
+
+	_, err = ParsePanicText([]byte("all good"), StackOptions{})
+	assert.ErrorIs(t, err, ErrNoPanic)
+}
+
+func TestParsedPanicRendering(t *testing.T) {
+	t.Parallel()
+
+	sv, err := ParsePanicText([]byte(`fatal error: all goroutines are asleep - deadlock!
+
+goroutine 1 [chan receive]:
+main.main()
+	/tmp/pp/main.go:4 +0x18
+`), StackOptions{})
+	assert.NoError(t, err)
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	sink.Error("child crashed", slog.Any(StackAttrName, sv))
+	assert.Equal(t, `{"time":"","level":"ERROR","msg":"child crashed","stack":[`+
+		`{"panic_msg":"all goroutines are asleep - deadlock!"},`+
+		`{"fl":"main/main.go:4","fn":"main"}]}`, sink.Get())
+
+	data := &bytes.Buffer{}
+	pretty := NewPrettySink(data, slog.LevelInfo, false)
+	slog.New(pretty.GetHandler()).Error("child crashed", slog.Any(StackAttrName, sv))
+	assert.Equal(t, `ERROR  panic_parser_test.go:77  child crashed
+	panic: all goroutines are asleep - deadlock!
+	main/main.go:4 (main)`, strings.TrimSpace(removeTimes(data.String())))
+}
+
+func TestParseTruncatedPanic(t *testing.T) {
+	t.Parallel()
+
+	// The dump is cut in the middle of the stack, without the trailing blank line
+	sv, err := ParsePanicText([]byte("panic: boom\ngoroutine 1 [running]:\nmain.main()\n\t/tmp/pp/main.go:4 +0x18\nmain.ru"),
+		StackOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []StackElement{{Msg: "boom"}, {Fl: "main/main.go:4", Fn: "main"}}, sv.JSONStack())
+
+	// The dump is cut right after the message
+	sv, err = ParsePanicText([]byte("fatal error: out of memory\n[signal SIGSEGV"), StackOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []StackElement{{Msg: "out of memory [signal SIGSEGV"}}, sv.JSONStack())
+}
@@ -67,28 +67,40 @@ func (p *PinpointLogLevels) WithEnvironmentOverrides() *PinpointLogLevels {
 
 func (p *PinpointLogLevels) WithEnvironmentListOverrides(env []string) *PinpointLogLevels {
 	for _, e := range env {
-		if !strings.HasPrefix(e, TIDBITS_ENV_PREFIX) {
+		// The named logger levels share the prefix
+		if strings.HasPrefix(e, TIDBITS_NAMED_ENV_PREFIX) {
 			continue
 		}
-		parts := strings.SplitN(e, "=", 2)
-		if len(parts) != 2 || parts[1] == "" {
-			continue
+		lvl, prefixes, ok := parseEnvOverride(e, TIDBITS_ENV_PREFIX)
+		if ok {
+			p.WithOverride(lvl, prefixes...)
 		}
+	}
 
-		logLevelName := strings.TrimPrefix(parts[0], TIDBITS_ENV_PREFIX)
-		logLevelName = strings.ReplaceAll(logLevelName, "_PLUS_", "+")
-		logLevelName = strings.ReplaceAll(logLevelName, "_MINUS_", "-")
+	return p
+}
 
-		var lvl slog.Level
-		err := (&lvl).UnmarshalText([]byte(logLevelName))
-		if err != nil {
-			panic("failed to parse the Tidbit logging override: " + parts[0] + ", err=" + err.Error())
-		}
+// Parse the environment variable like "<prefix>WARN_PLUS_2=value1,value2"
+func parseEnvOverride(e string, prefix string) (slog.Level, []string, bool) {
+	if !strings.HasPrefix(e, prefix) {
+		return 0, nil, false
+	}
+	parts := strings.SplitN(e, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return 0, nil, false
+	}
 
-		p.WithOverride(lvl, strings.Split(parts[1], ",")...)
+	logLevelName := strings.TrimPrefix(parts[0], prefix)
+	logLevelName = strings.ReplaceAll(logLevelName, "_PLUS_", "+")
+	logLevelName = strings.ReplaceAll(logLevelName, "_MINUS_", "-")
+
+	var lvl slog.Level
+	err := (&lvl).UnmarshalText([]byte(logLevelName))
+	if err != nil {
+		panic("failed to parse the Tidbit logging override: " + parts[0] + ", err=" + err.Error())
 	}
 
-	return p
+	return lvl, strings.Split(parts[1], ","), true
 }
 
 func (p *PinpointLogLevels) LevelForLocation(loc string) (slog.Level, bool) {
@@ -112,6 +124,12 @@ func (p *PinpointLogLevels) LevelForLocation(loc string) (slog.Level, bool) {
 	return lvl, found
 }
 
+func (p *PinpointLogLevels) numOverrides() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return len(p.prefixes)
+}
+
 func (p *PinpointLogLevels) PrintConfig(c context.Context, l *slog.Logger) {
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
@@ -167,12 +167,24 @@ func (p *PrettySink) prettyPrint(logStr []byte) error {
 	}
 
 	// Print the rest of the fields
-	var stack any
-	for k, v := range fields {
+	// Sort the keys to make the output stable
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var stack, goroutines any
+	for _, k := range keys {
+		v := fields[k]
 		if stack == nil && k == StackAttrName {
 			stack = v
 			continue
 		}
+		if goroutines == nil && k == GoroutinesAttrName {
+			goroutines = v
+			continue
+		}
 
 		entry.WriteString(k)
 		entry.WriteString("=")
@@ -180,18 +192,29 @@ func (p *PrettySink) prettyPrint(logStr []byte) error {
 		entry.WriteString(p.separator)
 	}
 
-	if stack != nil {
-		if p.printStack(entry, stack) {
-			entry.Truncate(entry.Len() - 1) // Remove the trailing newline
-		} else {
-			// We failed to do nice stack printing, so just print it as a regular field
-			entry.WriteString(StackAttrName)
-			entry.WriteString("=")
-			entry.WriteString(fmt.Sprint(stack))
-			entry.WriteString(p.separator)
-			// This is the last element, so we need to remove the trailing separator
+	// Multi-line values are printed after all the regular fields
+	multiline := bytes.NewBuffer(nil)
+	fallback := false
+	if stack != nil && !p.printStack(multiline, stack) {
+		// We failed to do nice stack printing, so just print it as a regular field
+		p.printField(entry, StackAttrName, stack)
+		fallback = true
+	}
+	if goroutines != nil && !p.printGoroutines(multiline, goroutines) {
+		p.printField(entry, GoroutinesAttrName, goroutines)
+		fallback = true
+	}
+
+	if multiline.Len() > 0 {
+		// Remove the trailing separator
+		if entry.Len() > len(p.separator) {
 			entry.Truncate(entry.Len() - len(p.separator))
 		}
+		entry.WriteString("\n")
+		entry.Write(multiline.Bytes()[:multiline.Len()-1]) // Without the trailing newline
+	} else if fallback {
+		// This is the last element, so we need to remove the trailing separator
+		entry.Truncate(entry.Len() - len(p.separator))
 	}
 
 	entry.WriteString("\n")
@@ -221,6 +244,13 @@ func (p *PrettySink) formatLevel(levelVal string) string {
 	return ColorGray + levelVal + ColorReset
 }
 
+func (p *PrettySink) printField(entry *bytes.Buffer, key string, val any) {
+	entry.WriteString(key)
+	entry.WriteString("=")
+	entry.WriteString(fmt.Sprint(val))
+	entry.WriteString(p.separator)
+}
+
 func (p *PrettySink) printStack(entry *bytes.Buffer, stack any) bool {
 	stackEntry := bytes.NewBuffer(nil)
 
@@ -242,19 +272,65 @@ func (p *PrettySink) printStack(entry *bytes.Buffer, stack any) bool {
 				continue
 			}
 		}
-		fl := valAsStr(elem, "fl")
-		fn := valAsStr(elem, "fn")
-		if fl == "" || fn == "" {
+		if !p.printStackElement(stackEntry, "\t", elem) {
 			return false
 		}
-		stackEntry.WriteString(fmt.Sprintf("\t%s (%s)\n", fl, fn))
 	}
 
-	// Remove the trailing separator
-	if entry.Len() > len(p.separator) {
-		entry.Truncate(entry.Len() - len(p.separator))
+	entry.Write(stackEntry.Bytes())
+	return true
+}
+
+func (p *PrettySink) printStackElement(entry *bytes.Buffer, indent string, elem map[string]any) bool {
+	fl := valAsStr(elem, "fl")
+	fn := valAsStr(elem, "fn")
+	if fl == "" || fn == "" {
+		return false
+	}
+	entry.WriteString(fmt.Sprintf("%s%s (%s)", indent, fl, fn))
+	if rep := valAsStr(elem, "rep"); rep != "" {
+		entry.WriteString(" x" + rep)
+	}
+	if own, _ := elem["own"].(bool); own {
+		entry.WriteString(" <--")
 	}
 	entry.WriteString("\n")
-	entry.WriteString(stackEntry.String())
+	return true
+}
+
+// Print the goroutine dump, the data is the JSON-decoded list of GoroutineStack
+func (p *PrettySink) printGoroutines(entry *bytes.Buffer, goroutines any) bool {
+	dumpEntry := bytes.NewBuffer(nil)
+
+	grList, ok := goroutines.([]any)
+	if !ok {
+		return false
+	}
+
+	for _, curGr := range grList {
+		grMap, ok := curGr.(map[string]any)
+		if !ok {
+			return false
+		}
+		// Round-trip through JSON to get the typed structure
+		data, err := json.Marshal(grMap)
+		if err != nil {
+			return false
+		}
+		var gr GoroutineStack
+		if json.Unmarshal(data, &gr) != nil {
+			return false
+		}
+
+		dumpEntry.WriteString("\t" + gr.Header() + "\n")
+		for _, e := range gr.Stack {
+			dumpEntry.WriteString("\t\t" + e.Fl + " (" + e.Fn + ")\n")
+		}
+		if gr.CreatedBy != nil {
+			dumpEntry.WriteString("\t\tcreated by " + gr.CreatedBy.Fl + " (" + gr.CreatedBy.Fn + ")\n")
+		}
+	}
+
+	entry.Write(dumpEntry.Bytes())
 	return true
 }
@@ -39,9 +39,9 @@ func TestPrettySinkStacks(t *testing.T) {
 
 	expected := `[31mERROR[0m  pretty_sink_test.go:37  Happened  key=42
 	panic: it's exploding
-	github.com/Cyberax/slog-tidbits/tidbits/stacks.go:27 (StackTraceAttr)
+	github.com/Cyberax/slog-tidbits/tidbits/stacks.go:57 (StackTraceAttr)
 	github.com/Cyberax/slog-tidbits/tidbits/pretty_sink_test.go:37 (TestPrettySinkStacks)
-	testing/testing.go:1689 (tRunner)`
+	testing/testing.go:2193 (tRunner)`
 
 	assert.Equal(t, expected, removeTimes(data.String()))
 }
@@ -0,0 +1,342 @@
+package tidbits
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CapturedRecord is a log record with the resolved attributes. The attributes added with
+// Logger.With are merged with the record attributes, and the groups are kept as the group values.
+type CapturedRecord struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	PC      uintptr
+	Attrs   []slog.Attr
+}
+
+// Attr finds the attribute value by its key, the keys within the groups are separated
+// by dots: "group.subgroup.key"
+func (c *CapturedRecord) Attr(key string) (slog.Value, bool) {
+	attrs := c.Attrs
+	for {
+		head, rest, nested := strings.Cut(key, ".")
+		idx := slices.IndexFunc(attrs, func(a slog.Attr) bool {
+			return a.Key == key || (nested && a.Key == head && a.Value.Kind() == slog.KindGroup)
+		})
+		if idx == -1 {
+			return slog.Value{}, false
+		}
+		if attrs[idx].Key == key {
+			return attrs[idx].Value, true
+		}
+		attrs, key = attrs[idx].Value.Group(), rest
+	}
+}
+
+// HasAttr checks that the record has the attribute with the specified value
+func (c *CapturedRecord) HasAttr(key string, value any) bool {
+	val, ok := c.Attr(key)
+	return ok && valuesEqual(val, value)
+}
+
+// FlatAttrs returns the attributes with the groups flattened into the dotted keys
+func (c *CapturedRecord) FlatAttrs() []slog.Attr {
+	return flattenAttrs(nil, "", c.Attrs)
+}
+
+func flattenAttrs(res []slog.Attr, prefix string, attrs []slog.Attr) []slog.Attr {
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			res = flattenAttrs(res, prefix+a.Key+".", a.Value.Group())
+			continue
+		}
+		res = append(res, slog.Attr{Key: prefix + a.Key, Value: a.Value})
+	}
+	return res
+}
+
+// String formats the record in a single line: `INFO "message" key=value group.key=value`
+func (c *CapturedRecord) String() string {
+	var res strings.Builder
+	res.WriteString(c.Level.String())
+	res.WriteString(" ")
+	res.WriteString(fmt.Sprintf("%q", c.Message))
+	for _, a := range c.FlatAttrs() {
+		res.WriteString(" ")
+		res.WriteString(a.String())
+	}
+	return res.String()
+}
+
+func valuesEqual(val slog.Value, expected any) bool {
+	ev, ok := expected.(slog.Value)
+	if !ok {
+		ev = slog.AnyValue(expected)
+	}
+	ev = ev.Resolve()
+	if val.Kind() != ev.Kind() {
+		return false
+	}
+	return reflect.DeepEqual(val.Any(), ev.Any())
+}
+
+// RecordMatcher describes the expected log record, all the conditions must match
+type RecordMatcher struct {
+	conds []recordCond
+}
+
+type recordCond struct {
+	descr string
+	match func(r *CapturedRecord) bool
+}
+
+// Expect creates an empty matcher that matches any record
+func Expect() *RecordMatcher {
+	return &RecordMatcher{}
+}
+
+func (m *RecordMatcher) add(descr string, match func(r *CapturedRecord) bool) *RecordMatcher {
+	return &RecordMatcher{conds: append(slices.Clone(m.conds), recordCond{descr: descr, match: match})}
+}
+
+func (m *RecordMatcher) Msg(msg string) *RecordMatcher {
+	return m.add(fmt.Sprintf("msg=%q", msg), func(r *CapturedRecord) bool {
+		return r.Message == msg
+	})
+}
+
+func (m *RecordMatcher) MsgContains(substr string) *RecordMatcher {
+	return m.add(fmt.Sprintf("msg~%q", substr), func(r *CapturedRecord) bool {
+		return strings.Contains(r.Message, substr)
+	})
+}
+
+func (m *RecordMatcher) Level(lvl slog.Level) *RecordMatcher {
+	return m.add("level="+lvl.String(), func(r *CapturedRecord) bool {
+		return r.Level == lvl
+	})
+}
+
+func (m *RecordMatcher) MinLevel(lvl slog.Level) *RecordMatcher {
+	return m.add("level>="+lvl.String(), func(r *CapturedRecord) bool {
+		return r.Level >= lvl
+	})
+}
+
+func (m *RecordMatcher) ExpectAttr(key string, value any) *RecordMatcher {
+	return m.add(slog.Any(key, value).String(), func(r *CapturedRecord) bool {
+		return r.HasAttr(key, value)
+	})
+}
+
+func (m *RecordMatcher) HasAttr(key string) *RecordMatcher {
+	return m.add(key+"=*", func(r *CapturedRecord) bool {
+		_, ok := r.Attr(key)
+		return ok
+	})
+}
+
+func (m *RecordMatcher) Matches(r *CapturedRecord) bool {
+	for _, c := range m.conds {
+		if !c.match(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Mismatches describes the conditions that the record doesn't match
+func (m *RecordMatcher) Mismatches(r *CapturedRecord) []string {
+	var res []string
+	for _, c := range m.conds {
+		if !c.match(r) {
+			res = append(res, c.descr)
+		}
+	}
+	return res
+}
+
+func (m *RecordMatcher) String() string {
+	descrs := make([]string, 0, len(m.conds))
+	for _, c := range m.conds {
+		descrs = append(descrs, c.descr)
+	}
+	return "{" + strings.Join(descrs, ", ") + "}"
+}
+
+// RecordingLogger is a logger that stores the structured log records in memory. It's intended to
+// be used in unit tests, to check the logged records without matching the serialized text.
+type RecordingLogger struct {
+	*slog.Logger
+	store *recordStore
+}
+
+type recordStore struct {
+	mtx     sync.Mutex
+	level   slog.Leveler
+	records []CapturedRecord
+	// The records are passed to the callback instead of being stored if it's set
+	callback func(CapturedRecord)
+}
+
+func NewRecordingLogger(lvl slog.Level) *RecordingLogger {
+	store := &recordStore{level: lvl}
+	return &RecordingLogger{
+		Logger: slog.New(&recordingHandler{store: store}),
+		store:  store,
+	}
+}
+
+// NewCapturingHandler creates the handler that passes the captured records to the callback, the
+// attributes and groups of the handler are merged into the records the same way as in
+// RecordingLogger. The callback can be called concurrently.
+func NewCapturingHandler(lvl slog.Leveler, callback func(CapturedRecord)) slog.Handler {
+	return &recordingHandler{store: &recordStore{level: lvl, callback: callback}}
+}
+
+// Records returns the snapshot of the captured records
+func (r *RecordingLogger) Records() []CapturedRecord {
+	r.store.mtx.Lock()
+	defer r.store.mtx.Unlock()
+	return slices.Clone(r.store.records)
+}
+
+// Reset removes all the captured records
+func (r *RecordingLogger) Reset() {
+	r.store.mtx.Lock()
+	defer r.store.mtx.Unlock()
+	r.store.records = nil
+}
+
+// Find returns the records that match the matcher
+func (r *RecordingLogger) Find(m *RecordMatcher) []CapturedRecord {
+	var res []CapturedRecord
+	for _, rec := range r.Records() {
+		if m.Matches(&rec) {
+			res = append(res, rec)
+		}
+	}
+	return res
+}
+
+func (r *RecordingLogger) FindByMsg(msg string) []CapturedRecord {
+	return r.Find(Expect().Msg(msg))
+}
+
+// AtLevel returns the records with exactly the specified level
+func (r *RecordingLogger) AtLevel(lvl slog.Level) []CapturedRecord {
+	return r.Find(Expect().Level(lvl))
+}
+
+// AtLeast returns the records with the level greater or equal than the specified one
+func (r *RecordingLogger) AtLeast(lvl slog.Level) []CapturedRecord {
+	return r.Find(Expect().MinLevel(lvl))
+}
+
+// groupOrAttrs is either the group name or the list of attributes added to the handler
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+type recordingHandler struct {
+	store *recordStore
+	goas  []groupOrAttrs
+}
+
+var _ slog.Handler = &recordingHandler{}
+
+func (h *recordingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.store.level.Level()
+}
+
+func (h *recordingHandler) Handle(ctx context.Context, record slog.Record) error {
+	captured := h.capture(record)
+	if h.store.callback != nil {
+		h.store.callback(captured)
+		return nil
+	}
+
+	h.store.mtx.Lock()
+	defer h.store.mtx.Unlock()
+	h.store.records = append(h.store.records, captured)
+	return nil
+}
+
+// Convert the record into CapturedRecord, merging it with the handler attributes and groups
+func (h *recordingHandler) capture(record slog.Record) CapturedRecord {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = appendResolved(attrs, a)
+		return true
+	})
+
+	// Wrap the attributes into the groups, starting from the innermost one
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		if goa.group != "" {
+			if len(attrs) != 0 {
+				attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+			}
+			continue
+		}
+		resolved := make([]slog.Attr, 0, len(goa.attrs)+len(attrs))
+		for _, a := range goa.attrs {
+			resolved = appendResolved(resolved, a)
+		}
+		attrs = append(resolved, attrs...)
+	}
+
+	return CapturedRecord{
+		Time:    record.Time,
+		Level:   record.Level,
+		Message: record.Message,
+		PC:      record.PC,
+		Attrs:   attrs,
+	}
+}
+
+// Resolve the LogValuers and follow the slog rules: the empty attributes are dropped,
+// the empty groups are dropped, and the groups with empty keys are inlined.
+func appendResolved(res []slog.Attr, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		if a.Equal(slog.Attr{}) {
+			return res
+		}
+		return append(res, a)
+	}
+
+	var group []slog.Attr
+	for _, ga := range a.Value.Group() {
+		group = appendResolved(group, ga)
+	}
+	if len(group) == 0 {
+		return res
+	}
+	if a.Key == "" {
+		return append(res, group...)
+	}
+	return append(res, slog.Attr{Key: a.Key, Value: slog.GroupValue(group...)})
+}
+
+func (h *recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &recordingHandler{store: h.store, goas: append(slices.Clip(h.goas), groupOrAttrs{attrs: attrs})}
+}
+
+func (h *recordingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &recordingHandler{store: h.store, goas: append(slices.Clip(h.goas), groupOrAttrs{group: name})}
+}
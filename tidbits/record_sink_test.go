@@ -0,0 +1,38 @@
+package tidbits
+
+import (
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+func TestRecordingLogger(t *testing.T) {
+	t.Parallel()
+
+	rl := NewRecordingLogger(slog.LevelInfo)
+	rl.Debug("skipped")
+	rl.Info("hello", slog.Int("count", 42))
+
+	sub := rl.With(slog.String("component", "db")).WithGroup("req")
+	sub.Warn("slow query", slog.Group("query", slog.String("table", "users")),
+		slog.Duration("took", 5), slog.Group("empty"))
+	sub.Error("failed", slog.Any("val", &AttrLevel{level: slog.LevelWarn}))
+
+	assert.Equal(t, 3, len(rl.Records()))
+	assert.Empty(t, rl.FindByMsg("skipped"))
+	assert.Equal(t, 2, len(rl.AtLeast(slog.LevelWarn)))
+	assert.Equal(t, 1, len(rl.AtLevel(slog.LevelError)))
+
+	slow := rl.FindByMsg("slow query")[0]
+	assert.True(t, slow.HasAttr("component", "db"))
+	assert.True(t, slow.HasAttr("req.query.table", "users"))
+	_, ok := slow.Attr("req.empty")
+	assert.False(t, ok)
+	assert.Equal(t, `WARN "slow query" component=db req.query.table=users req.took=5ns`, slow.String())
+
+	// The LogValuer is resolved
+	assert.Equal(t, 1, len(rl.Find(Expect().ExpectAttr("req.val", "WARN"))))
+
+	rl.Reset()
+	assert.Empty(t, rl.Records())
+}
@@ -0,0 +1,76 @@
+package tidbits
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const RedactedValue = "[REDACTED]"
+
+// RedactionRule replaces the attribute value, it returns false if the attribute is not affected
+type RedactionRule interface {
+	Redact(key string, v slog.Value) (slog.Value, bool)
+}
+
+type keyRedaction struct {
+	keys []string
+}
+
+// RedactKeys replaces the values of the attributes with the given keys (case-insensitive),
+// including the attributes inside groups. The keys flattened by SlogConvenience.WithGroup
+// ("user.password") also match by their last segment.
+func RedactKeys(keys ...string) RedactionRule {
+	lower := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lower = append(lower, strings.ToLower(k))
+	}
+	return &keyRedaction{keys: lower}
+}
+
+func (k *keyRedaction) Redact(key string, v slog.Value) (slog.Value, bool) {
+	key = strings.ToLower(key)
+	lastSegment := key[strings.LastIndexByte(key, '.')+1:]
+	if !slices.Contains(k.keys, key) && !slices.Contains(k.keys, lastSegment) {
+		return v, false
+	}
+	return slog.StringValue(RedactedValue), true
+}
+
+type patternRedaction struct {
+	re *regexp.Regexp
+}
+
+// RedactPattern replaces the matches of the pattern inside the string values
+func RedactPattern(re *regexp.Regexp) RedactionRule {
+	return &patternRedaction{re: re}
+}
+
+func (p *patternRedaction) Redact(key string, v slog.Value) (slog.Value, bool) {
+	if v.Kind() != slog.KindString || !p.re.MatchString(v.String()) {
+		return v, false
+	}
+	return slog.StringValue(p.re.ReplaceAllString(v.String(), RedactedValue)), true
+}
+
+// Apply the redaction rules to the attributes, the slice is modified in place
+func redactAttrs(rules []RedactionRule, attrs []slog.Attr) []slog.Attr {
+	for i, a := range attrs {
+		attrs[i] = redactAttr(rules, a)
+	}
+	return attrs
+}
+
+func redactAttr(rules []RedactionRule, a slog.Attr) slog.Attr {
+	for _, r := range rules {
+		if v, ok := r.Redact(a.Key, a.Value); ok {
+			return slog.Attr{Key: a.Key, Value: v}
+		}
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		group := slices.Clone(a.Value.Group())
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redactAttrs(rules, group)...)}
+	}
+	return a
+}
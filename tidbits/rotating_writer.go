@@ -0,0 +1,464 @@
+package tidbits
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Compression int
+
+const (
+	CompressNone Compression = iota
+	CompressGzip
+	CompressZstd
+)
+
+func (c Compression) suffix() string {
+	switch c {
+	case CompressGzip:
+		return ".gz"
+	case CompressZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// The timestamp of the rotated files: app.log -> app-20240818T123847.271462.log
+const rotationTimeFormat = "20060102T150405.000000"
+
+type RotationOptions struct {
+	// MaxSize rotates the file before it grows past this size in bytes, 0 disables the size rotation
+	MaxSize int64
+	// Interval rotates the file when the wall-clock time crosses the interval boundary
+	// (e.g. at midnight UTC for 24*time.Hour), 0 disables the time rotation
+	Interval time.Duration
+
+	// Compression for the rotated files, they are compressed in the background
+	Compression Compression
+
+	// MaxBackups is the number of the rotated files to keep, 0 keeps all of them
+	MaxBackups int
+	// MaxAge removes the rotated files that are older than this, 0 keeps all of them
+	MaxAge time.Duration
+
+	// Perm is the permission for the new files, 0644 by default
+	Perm os.FileMode
+
+	// MaxPartialLine is the limit for the incomplete line that is held until its newline arrives,
+	// the longer line is written out terminated. 1MiB by default.
+	MaxPartialLine int
+
+	// The time source and the file renaming for tests
+	now    func() time.Time
+	rename func(oldPath, newPath string) error
+}
+
+// RotatingWriter writes the log into the file, rotating it by size or time. It only rotates
+// between the complete lines: the incomplete tail of the written data is held until its newline
+// arrives. PrettySink and the slog handlers write each record with a single Write call while
+// holding their mutex, so the records are never split across the files.
+type RotatingWriter struct {
+	path string
+	opts RotationOptions
+
+	mtx sync.Mutex
+	// The file is nil if it has failed to open after the rotation, it's retried on the next write
+	file     *os.File
+	closed   bool
+	size     int64
+	openedAt time.Time
+	partial  []byte
+	// The size at the failed rotation, the size rotation is retried once MaxSize more is written
+	failedAtSize int64
+
+	// Compression and cleanup of the rotated files run in the background, one at a time
+	bgMtx sync.Mutex
+	bgWg  sync.WaitGroup
+}
+
+var _ io.WriteCloser = &RotatingWriter{}
+
+func NewRotatingWriter(path string, opts RotationOptions) (*RotatingWriter, error) {
+	if opts.Perm == 0 {
+		opts.Perm = 0644
+	}
+	if opts.MaxPartialLine == 0 {
+		opts.MaxPartialLine = 1024 * 1024
+	}
+	if opts.now == nil {
+		opts.now = time.Now
+	}
+	if opts.rename == nil {
+		opts.rename = os.Rename
+	}
+	w := &RotatingWriter{path: path, opts: opts}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	err := w.open()
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Open the file for appending. If the previous process crashed in the middle of a line, the
+// partial line is terminated, so that the new records start on their own lines.
+func (w *RotatingWriter) open() error {
+	err := os.MkdirAll(filepath.Dir(w.path), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, w.opts.Perm)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.failedAtSize = 0
+	w.openedAt = w.opts.now()
+	if w.size == 0 {
+		return nil
+	}
+
+	// The existing file belongs to the interval of its last write
+	w.openedAt = info.ModTime()
+	last := make([]byte, 1)
+	_, err = file.ReadAt(last, w.size-1)
+	if err == nil && last[0] != '\n' {
+		_, err = file.Write([]byte("\n"))
+		w.size++
+	}
+	return err
+}
+
+// Open the file if the previous rotation has failed to open it
+func (w *RotatingWriter) ensureOpen() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file != nil {
+		return nil
+	}
+	return w.open()
+}
+
+func (w *RotatingWriter) Write(data []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	err := w.ensureOpen()
+	if err != nil {
+		return 0, err
+	}
+
+	lastEol := bytes.LastIndexByte(data, '\n')
+	if lastEol == -1 && len(w.partial)+len(data) < w.opts.MaxPartialLine {
+		w.partial = append(w.partial, data...)
+		return len(data), nil
+	}
+
+	var chunk, rest []byte
+	if lastEol == -1 {
+		// The line is too long to be held, so it's written out terminated
+		chunk = append(slices.Clip(data), '\n')
+	} else {
+		chunk = data[:lastEol+1]
+		rest = data[lastEol+1:]
+	}
+	buffered := len(w.partial)
+	if buffered != 0 {
+		chunk = append(slices.Clip(w.partial), chunk...)
+	}
+
+	if w.shouldRotate(int64(len(chunk))) {
+		// The writes go into the old file if only the renaming has failed
+		err := w.rotate()
+		if err != nil && w.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(chunk)
+	w.size += int64(n)
+	if err != nil {
+		// The previously buffered bytes have been accepted, so their unwritten part is kept for
+		// the next write. The unwritten part of the data is not consumed, it's up to the caller.
+		w.partial = slices.Clone(w.partial[min(n, buffered):])
+		return min(max(n-buffered, 0), len(data)), err
+	}
+	w.partial = slices.Clone(rest)
+	return len(data), nil
+}
+
+func (w *RotatingWriter) shouldRotate(toWrite int64) bool {
+	if w.size == 0 {
+		// Don't create the empty files
+		return false
+	}
+	if w.opts.MaxSize > 0 && w.size-w.failedAtSize+toWrite > w.opts.MaxSize {
+		return true
+	}
+	if w.opts.Interval > 0 {
+		return !w.opts.now().Truncate(w.opts.Interval).Equal(w.openedAt.Truncate(w.opts.Interval))
+	}
+	return false
+}
+
+// Rotate forces the rotation of the current file
+func (w *RotatingWriter) Rotate() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	err := w.ensureOpen()
+	if err != nil {
+		return err
+	}
+	return w.rotate()
+}
+
+// Rotate the file, if the new file can't be opened, the next write retries opening it. If the
+// file can't be renamed, the writes go into the old file, and the rotation is retried at the next
+// interval or once MaxSize more is written.
+func (w *RotatingWriter) rotate() error {
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return err
+	}
+
+	now := w.opts.now()
+	rotated := w.rotatedName(now)
+	renameErr := w.opts.rename(w.path, rotated)
+	err = w.open()
+	if renameErr != nil && err == nil {
+		w.failedAtSize = w.size
+		w.openedAt = now
+	}
+	if renameErr != nil || err != nil {
+		return errors.Join(renameErr, err)
+	}
+
+	w.bgWg.Add(1)
+	go func() {
+		defer w.bgWg.Done()
+		w.bgMtx.Lock()
+		defer w.bgMtx.Unlock()
+		// The errors can't be reported anywhere, the file is left uncompressed
+		_ = compressRotated(rotated, w.opts.Compression, w.opts.Perm)
+		w.removeOldFiles(now)
+	}()
+	return nil
+}
+
+func (w *RotatingWriter) rotatedName(now time.Time) string {
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext)
+	stamp := now.UTC().Format(rotationTimeFormat)
+
+	name := base + "-" + stamp + ext
+	for i := 1; fileExists(name) || fileExists(name+w.opts.Compression.suffix()); i++ {
+		name = base + "-" + stamp + "." + strconv.Itoa(i) + ext
+	}
+	return name
+}
+
+// Reopen closes and reopens the file, it's used when the file is moved by an external tool like
+// logrotate. The incomplete line is kept and written into the new file. If the new file can't be
+// opened, the writer keeps writing into the old one.
+func (w *RotatingWriter) Reopen() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	old := w.file
+	err := w.open()
+	if w.file == old || old == nil {
+		return err
+	}
+	return errors.Join(err, old.Close())
+}
+
+// ReopenOnSignal reopens the file when the process receives one of the signals (SIGHUP by default).
+// Call the returned function to remove the handler, it can be called several times.
+func (w *RotatingWriter) ReopenOnSignal(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigChan, signals...)
+
+	go func() {
+		for {
+			select {
+			case <-sigChan:
+				_ = w.Reopen()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sigChan)
+			close(done)
+		})
+	}
+}
+
+// Close writes the incomplete line (terminating it), closes the file and waits for the
+// background compression to finish
+func (w *RotatingWriter) Close() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return nil
+	}
+	defer w.bgWg.Wait()
+
+	var err error
+	if len(w.partial) != 0 {
+		err = w.ensureOpen()
+		if err == nil {
+			_, err = w.file.Write(append(w.partial, '\n'))
+		}
+		w.partial = nil
+	}
+	w.closed = true
+	if w.file != nil {
+		if closeErr := w.file.Close(); err == nil {
+			err = closeErr
+		}
+		w.file = nil
+	}
+	return err
+}
+
+// Compress the file into a temporary file, and then atomically rename it, so that a crash never
+// leaves a truncated archive
+func compressRotated(name string, compression Compression, perm os.FileMode) error {
+	if compression == CompressNone {
+		return nil
+	}
+
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	target := name + compression.suffix()
+	tmp, err := os.OpenFile(target+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	var enc io.WriteCloser
+	if compression == CompressGzip {
+		enc = gzip.NewWriter(tmp)
+	} else {
+		enc, err = zstd.NewWriter(tmp)
+		if err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+
+	_, err = io.Copy(enc, src)
+	if err == nil {
+		err = enc.Close()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), target)
+	if err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// Remove the rotated files beyond MaxBackups or older than MaxAge
+func (w *RotatingWriter) removeOldFiles(now time.Time) {
+	if w.opts.MaxBackups <= 0 && w.opts.MaxAge <= 0 {
+		return
+	}
+
+	type rotatedFile struct {
+		name string
+		at   time.Time
+	}
+	var files []rotatedFile
+
+	ext := filepath.Ext(w.path)
+	prefix := filepath.Base(strings.TrimSuffix(w.path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name, found := strings.CutPrefix(e.Name(), prefix)
+		if !found || e.IsDir() || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		if len(name) < len(rotationTimeFormat) {
+			continue
+		}
+		at, err := time.Parse(rotationTimeFormat, name[:len(rotationTimeFormat)])
+		if err != nil {
+			continue
+		}
+		files = append(files, rotatedFile{name: filepath.Join(filepath.Dir(w.path), e.Name()), at: at})
+	}
+
+	// Newest first
+	slices.SortFunc(files, func(a, b rotatedFile) int {
+		if c := b.at.Compare(a.at); c != 0 {
+			return c
+		}
+		return strings.Compare(b.name, a.name)
+	})
+
+	for i, f := range files {
+		if (w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups) ||
+			(w.opts.MaxAge > 0 && now.Sub(f.at) > w.opts.MaxAge) {
+			_ = os.Remove(f.name)
+		}
+	}
+}
+
+func fileExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
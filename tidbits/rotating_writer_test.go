@@ -0,0 +1,329 @@
+package tidbits
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRotatingWriterSize(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	w, err := NewRotatingWriter(filepath.Join(dir, "app.log"), RotationOptions{MaxSize: 20})
+	assert.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err = fmt.Fprintf(w, "line %d 123456789\n", i) // 18 bytes
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	files := listLogs(t, dir)
+	assert.Equal(t, 5, len(files))
+	assert.Equal(t, "app.log", files[len(files)-1])
+	assert.Equal(t, "line 4 123456789\n", readLog(t, filepath.Join(dir, "app.log")))
+	for i, f := range files[:4] {
+		assert.True(t, strings.HasPrefix(f, "app-") && strings.HasSuffix(f, ".log"))
+		assert.Equal(t, fmt.Sprintf("line %d 123456789\n", i), readLog(t, filepath.Join(dir, f)))
+	}
+}
+
+func TestRotatingWriterPartialLines(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	// The previous process has crashed in the middle of the line
+	assert.NoError(t, os.WriteFile(name, []byte("complete\ncrashed"), 0644))
+
+	w, err := NewRotatingWriter(name, RotationOptions{})
+	assert.NoError(t, err)
+
+	_, _ = w.Write([]byte("abc"))
+	_, _ = w.Write([]byte("def\nghi"))
+	assert.Equal(t, "complete\ncrashed\nabcdef\n", readLog(t, name))
+
+	// The partial line is moved into the new file on rotation
+	assert.NoError(t, w.Rotate())
+	_, _ = w.Write([]byte("jkl\n"))
+	assert.Equal(t, "ghijkl\n", readLog(t, name))
+
+	_, _ = w.Write([]byte("unterminated"))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "ghijkl\nunterminated\n", readLog(t, name))
+
+	_, err = w.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestRotatingWriterTimeAndRetention(t *testing.T) {
+	t.Parallel()
+
+	for _, compression := range []Compression{CompressGzip, CompressZstd} {
+		dir := t.TempDir()
+		now := time.Date(2024, 8, 18, 10, 0, 0, 0, time.UTC)
+		w, err := NewRotatingWriter(filepath.Join(dir, "app.log"), RotationOptions{
+			Interval: time.Hour, Compression: compression, MaxBackups: 2,
+			now: func() time.Time { return now },
+		})
+		assert.NoError(t, err)
+
+		for i := 0; i < 4; i++ {
+			_, _ = fmt.Fprintf(w, "hour %d\n", i)
+			_, _ = fmt.Fprintf(w, "same hour %d\n", i)
+			now = now.Add(time.Hour)
+		}
+		assert.NoError(t, w.Close())
+
+		suffix := compression.suffix()
+		assert.Equal(t, []string{"app-20240818T120000.000000.log" + suffix,
+			"app-20240818T130000.000000.log" + suffix, "app.log"}, listLogs(t, dir))
+		assert.Equal(t, "hour 2\nsame hour 2\n",
+			readLog(t, filepath.Join(dir, "app-20240818T130000.000000.log"+suffix)))
+		assert.Equal(t, "hour 3\nsame hour 3\n", readLog(t, filepath.Join(dir, "app.log")))
+	}
+}
+
+func TestRotatingWriterMaxAge(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	now := time.Date(2024, 8, 18, 10, 0, 0, 0, time.UTC)
+	w, err := NewRotatingWriter(filepath.Join(dir, "app.log"), RotationOptions{
+		MaxAge: 36 * time.Hour, now: func() time.Time { return now },
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, _ = fmt.Fprintf(w, "day %d\n", i)
+		assert.NoError(t, w.Rotate())
+		now = now.Add(24 * time.Hour)
+	}
+	_, _ = fmt.Fprintf(w, "day 3\n")
+	assert.NoError(t, w.Rotate())
+	assert.NoError(t, w.Close())
+
+	// The files rotated at days 0 and 1 are older than 36 hours
+	assert.Equal(t, []string{"app-20240820T100000.000000.log", "app-20240821T100000.000000.log", "app.log"},
+		listLogs(t, dir))
+	assert.Equal(t, "day 2\n", readLog(t, filepath.Join(dir, "app-20240820T100000.000000.log")))
+}
+
+func TestRotatingWriterReopen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	w, err := NewRotatingWriter(name, RotationOptions{})
+	assert.NoError(t, err)
+	defer func() { _ = w.Close() }()
+
+	_, _ = w.Write([]byte("before\n"))
+	// The file is moved away by logrotate, the writes still go into it until it's reopened
+	assert.NoError(t, os.Rename(name, name+".1"))
+	_, _ = w.Write([]byte("moved\n"))
+	assert.NoError(t, w.Reopen())
+	_, _ = w.Write([]byte("after\n"))
+
+	assert.Equal(t, "before\nmoved\n", readLog(t, name+".1"))
+	assert.Equal(t, "after\n", readLog(t, name))
+
+	// The handler can be removed several times
+	stop := w.ReopenOnSignal()
+	stop()
+	stop()
+}
+
+func TestRotatingWriterWithPrettySink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	w, err := NewRotatingWriter(filepath.Join(dir, "app.log"), RotationOptions{MaxSize: 1000})
+	assert.NoError(t, err)
+
+	logger := slog.New(NewPrettySink(w, slog.LevelInfo, false).GetHandler())
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				logger.Info("concurrent message", slog.Int("goroutine", i), slog.Int("num", j))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.NoError(t, w.Close())
+
+	total := 0
+	for _, f := range listLogs(t, dir) {
+		for _, line := range strings.Split(strings.TrimSuffix(readLog(t, filepath.Join(dir, f)), "\n"), "\n") {
+			assert.Contains(t, line, "concurrent message")
+			assert.Regexp(t, `num=\d+\s*$`, line)
+			total++
+		}
+	}
+	assert.Equal(t, 400, total)
+}
+
+func TestRotatingWriterFailedWrite(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotatingWriter(name, RotationOptions{})
+	assert.NoError(t, err)
+	_, _ = w.Write([]byte("abc"))
+
+	// The writes into a read-only handle fail, the data is not consumed
+	good := w.file
+	w.file, err = os.Open(name)
+	assert.NoError(t, err)
+	n, err := w.Write([]byte("def\n"))
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+	_ = w.file.Close()
+
+	// The buffered part is still written, the rejected data is not replayed
+	w.file = good
+	_, err = w.Write([]byte("ghi\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "abcghi\n", readLog(t, name))
+}
+
+func TestRotatingWriterFailedOpen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "sub", "app.log")
+	w, err := NewRotatingWriter(name, RotationOptions{})
+	assert.NoError(t, err)
+	_, _ = w.Write([]byte("first\n"))
+
+	// The directory is moved away, and its path is taken by a file, so the log can't be opened
+	assert.NoError(t, os.Rename(filepath.Join(dir, "sub"), filepath.Join(dir, "moved")))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub"), nil, 0644))
+
+	// The reopen fails, and the old file is still written into
+	assert.Error(t, w.Reopen())
+	_, err = w.Write([]byte("second\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", readLog(t, filepath.Join(dir, "moved", "app.log")))
+
+	// The rotation fails to open the new file, the next writes retry opening it
+	assert.Error(t, w.Rotate())
+	_, err = w.Write([]byte("lost\n"))
+	assert.Error(t, err)
+	assert.NoError(t, os.Remove(filepath.Join(dir, "sub")))
+	_, err = w.Write([]byte("third\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "third\n", readLog(t, name))
+
+	_, err = w.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestRotatingWriterFailedRename(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 8, 18, 10, 0, 0, 0, time.UTC)
+	renames := 0
+	failRename := true
+	w, err := NewRotatingWriter(name, RotationOptions{
+		MaxSize: 40,
+		now:     func() time.Time { return now },
+		rename: func(oldPath, newPath string) error {
+			renames++
+			if failRename {
+				return errors.New("rename failed")
+			}
+			return os.Rename(oldPath, newPath)
+		},
+	})
+	assert.NoError(t, err)
+
+	// The lines are written into the old file, the rotation is retried after 40 more bytes
+	for i := 0; i < 5; i++ {
+		_, err = fmt.Fprintf(w, "line %d 123456789\n", i) // 18 bytes
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, renames)
+	assert.Equal(t, "line 0 123456789\nline 1 123456789\nline 2 123456789\nline 3 123456789\n"+
+		"line 4 123456789\n", readLog(t, name))
+
+	// The forced rotation reports the error
+	assert.Error(t, w.Rotate())
+
+	// The next rotation is after 40 more bytes again
+	failRename = false
+	for i := 5; i < 8; i++ {
+		_, err = fmt.Fprintf(w, "line %d 123456789\n", i)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 4, renames)
+	assert.Equal(t, 2, len(listLogs(t, dir)))
+	assert.Equal(t, "line 7 123456789\n", readLog(t, name))
+}
+
+func TestRotatingWriterLongPartialLine(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotatingWriter(name, RotationOptions{MaxPartialLine: 8})
+	assert.NoError(t, err)
+
+	_, _ = w.Write([]byte("abcd"))
+	_, _ = w.Write([]byte("efgh"))
+	_, _ = w.Write([]byte("ij\n"))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "abcdefgh\nij\n", readLog(t, name))
+}
+
+func listLogs(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	var res []string
+	for _, e := range entries {
+		res = append(res, e.Name())
+	}
+	slices.Sort(res)
+	return res
+}
+
+func readLog(t *testing.T, name string) string {
+	file, err := os.Open(name)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer func() { _ = file.Close() }()
+
+	var rd io.Reader = file
+	switch filepath.Ext(name) {
+	case ".gz":
+		rd, err = gzip.NewReader(file)
+		assert.NoError(t, err)
+	case ".zst":
+		dec, err := zstd.NewReader(file)
+		assert.NoError(t, err)
+		defer dec.Close()
+		rd = dec
+	}
+	data, err := io.ReadAll(rd)
+	assert.NoError(t, err)
+	return string(data)
+}
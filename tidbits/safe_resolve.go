@@ -0,0 +1,175 @@
+package tidbits
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxLogValuerDepth is the maximum number of the nested LogValue calls for a single attribute,
+// it stops the LogValuers that return themselves
+const MaxLogValuerDepth = 100
+
+// MaxPendingResolves is the limit for the LogValuers that have missed their deadline and are
+// still running in their goroutines. Once it's reached, the new LogValuers are not started until
+// one of the abandoned ones returns, and they are timed out if that doesn't happen before their
+// deadline. The limit can be exceeded by the number of the concurrent logging calls, as the
+// LogValuers that are already running can still miss their deadline.
+const MaxPendingResolves = 64
+
+// The LogValuers that have been abandoned by their callers, but haven't returned yet
+var abandonedResolves struct {
+	sync.Mutex
+	count int
+	// Closed when an abandoned LogValuer returns
+	freed chan struct{}
+}
+
+// Wait until the number of the abandoned LogValuers is below the limit, it returns false if the
+// deadline has passed first
+func waitForResolveSlot(deadline <-chan time.Time) bool {
+	for {
+		abandonedResolves.Lock()
+		if abandonedResolves.count < MaxPendingResolves {
+			abandonedResolves.Unlock()
+			return true
+		}
+		if abandonedResolves.freed == nil {
+			abandonedResolves.freed = make(chan struct{})
+		}
+		freed := abandonedResolves.freed
+		abandonedResolves.Unlock()
+
+		select {
+		case <-freed:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+func abandonResolve() {
+	abandonedResolves.Lock()
+	defer abandonedResolves.Unlock()
+	abandonedResolves.count++
+}
+
+func releaseResolve() {
+	abandonedResolves.Lock()
+	defer abandonedResolves.Unlock()
+	abandonedResolves.count--
+	if abandonedResolves.freed != nil {
+		close(abandonedResolves.freed)
+		abandonedResolves.freed = nil
+	}
+}
+
+// The states of the LogValuer running with a deadline
+const (
+	resolveRunning int32 = iota
+	resolveDone
+	resolveAbandoned
+)
+
+// SafeResolve resolves the LogValuers the same way as SlogConvenience does, it's intended for the
+// handlers that need the attribute values before SlogConvenience has processed the record. The
+// panics are turned into the "!PANIC(msg)" values, and the LogValuers slower than the timeout
+// (if it's not zero) are replaced by the "!TIMEOUT(...)" strings. The StackValues are not resolved.
+func SafeResolve(v slog.Value, timeout time.Duration) slog.Value {
+	return safeResolve(v, timeout, 0)
+}
+
+// Resolve the LogValuers inside the attributes, including the ones nested in groups, the slice
+// is modified in place. The panics in the LogValuers are turned into the "!PANIC(msg)" values
+// with the stack trace of the panic.
+func safeResolveAttrs(attrs []slog.Attr, timeout time.Duration) []slog.Attr {
+	for i, a := range attrs {
+		attrs[i].Value = safeResolve(a.Value, timeout, 0)
+	}
+	return attrs
+}
+
+func safeResolve(v slog.Value, timeout time.Duration, depth int) slog.Value {
+	for v.Kind() == slog.KindLogValuer {
+		// The stack is rendered by the handlers, it can't panic
+		if _, ok := v.Any().(*StackValue); ok {
+			return v
+		}
+		if depth >= MaxLogValuerDepth {
+			return slog.StringValue(fmt.Sprintf("!RECURSION(%T)", v.Any()))
+		}
+		depth++
+		v = callLogValuer(v.LogValuer(), timeout)
+	}
+
+	if v.Kind() != slog.KindGroup {
+		return v
+	}
+
+	// Copy the group only if something inside it has been resolved
+	group := v.Group()
+	var resolved []slog.Attr
+	for i, a := range group {
+		if a.Value.Kind() != slog.KindLogValuer && a.Value.Kind() != slog.KindGroup {
+			continue
+		}
+		rv := safeResolve(a.Value, timeout, depth)
+		if resolved == nil {
+			resolved = append(make([]slog.Attr, 0, len(group)), group...)
+		}
+		resolved[i].Value = rv
+	}
+	if resolved == nil {
+		return v
+	}
+	return slog.GroupValue(resolved...)
+}
+
+func callLogValuer(lv slog.LogValuer, timeout time.Duration) slog.Value {
+	if timeout <= 0 {
+		return protectedLogValue(lv)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	if !waitForResolveSlot(timer.C) {
+		return slog.StringValue(fmt.Sprintf("!TIMEOUT(%T, too many pending)", lv))
+	}
+
+	// The slow LogValuer is left running in its goroutine, its result is discarded
+	var state atomic.Int32
+	res := make(chan slog.Value, 1)
+	go func() {
+		res <- protectedLogValue(lv)
+		if !state.CompareAndSwap(resolveRunning, resolveDone) {
+			releaseResolve()
+		}
+	}()
+
+	select {
+	case v := <-res:
+		return v
+	case <-timer.C:
+		abandonResolve()
+		if !state.CompareAndSwap(resolveRunning, resolveAbandoned) {
+			// The LogValuer has returned just now
+			releaseResolve()
+			return <-res
+		}
+		return slog.StringValue(fmt.Sprintf("!TIMEOUT(%T after %s)", lv, timeout))
+	}
+}
+
+func protectedLogValue(lv slog.LogValuer) (res slog.Value) {
+	defer func() {
+		if p := recover(); p != nil {
+			stack := NewStackValue(2, true, p)
+			res = slog.GroupValue(
+				slog.String("panic", fmt.Sprintf("!PANIC(%s)", PanicMsgToString(p))),
+				slog.Any(StackAttrName, stack))
+		}
+	}()
+	return lv.LogValue()
+}
@@ -0,0 +1,126 @@
+package tidbits
+
+import (
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type panickingValuer struct{}
+
+func (p panickingValuer) LogValue() slog.Value {
+	panic("boom")
+}
+
+type recursiveValuer struct{}
+
+func (r recursiveValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.Any("self", r))
+}
+
+type slowValuer struct {
+	release chan struct{}
+}
+
+func (s slowValuer) LogValue() slog.Value {
+	<-s.release
+	return slog.StringValue("slow")
+}
+
+func TestSafeResolvePanic(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{}, sink.Handler()))
+
+	logger.Info("panic", slog.Group("g", slog.Any("bad", panickingValuer{})), slog.Int("good", 1))
+
+	res := sink.Get()
+	assert.True(t, strings.HasPrefix(res,
+		`{"time":"","level":"INFO","msg":"panic","g":{"bad":{"panic":"!PANIC(boom)","stack":[{"panic_msg":"boom"}`), res)
+	assert.Contains(t, res, `{"fl":"github.com/Cyberax/slog-tidbits/tidbits.panickingValuer/safe_resolve_test.go:16","fn":"LogValue"}`)
+	assert.True(t, strings.HasSuffix(res, `]}},"good":1}`), res)
+}
+
+func TestSafeResolveRecursion(t *testing.T) {
+	t.Parallel()
+
+	v := safeResolve(slog.AnyValue(recursiveValuer{}), 0, 0)
+	for i := 0; i < MaxLogValuerDepth; i++ {
+		v = v.Group()[0].Value
+	}
+	assert.Equal(t, "!RECURSION(tidbits.recursiveValuer)", v.String())
+}
+
+func TestSafeResolveTimeout(t *testing.T) {
+	t.Parallel()
+
+	slow := slowValuer{release: make(chan struct{})}
+	defer close(slow.release)
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{ResolveTimeout: 10 * time.Millisecond}, sink.Handler()))
+	logger.Info("slow", slog.Any("v", slow))
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"slow","v":"!TIMEOUT(tidbits.slowValuer after 10ms)"}`, sink.Get())
+}
+
+func TestSafeResolvePendingLimit(t *testing.T) {
+	// Not parallel, the goroutine count is checked
+	slow := slowValuer{release: make(chan struct{})}
+	defer func() {
+		// Free the slots for the other tests
+		close(slow.release)
+		assert.Eventually(t, func() bool {
+			abandonedResolves.Lock()
+			defer abandonedResolves.Unlock()
+			return abandonedResolves.count == 0
+		}, time.Second, time.Millisecond)
+	}()
+
+	logger := slog.New(NewSlogConvenience(SlogOptions{ResolveTimeout: time.Millisecond},
+		NewNopLogger(slog.LevelInfo).Handler()))
+	before := runtime.NumGoroutine()
+	for i := 0; i < 3*MaxPendingResolves; i++ {
+		logger.Info("slow", slog.Any("v", slow))
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine()-before, MaxPendingResolves)
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger = slog.New(NewSlogConvenience(SlogOptions{ResolveTimeout: 10 * time.Millisecond}, sink.Handler()))
+	logger.Info("slow", slog.Any("v", slow))
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"slow","v":"!TIMEOUT(tidbits.slowValuer, too many pending)"}`, sink.Get())
+}
+
+type sleepyValuer struct{}
+
+func (s sleepyValuer) LogValue() slog.Value {
+	time.Sleep(5 * time.Millisecond)
+	return slog.StringValue("sleepy")
+}
+
+func TestSafeResolveConcurrent(t *testing.T) {
+	t.Parallel()
+
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{ResolveTimeout: time.Second}, sink.Handler()))
+
+	// The LogValuers that are still running within their deadline don't count against the limit
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3*MaxPendingResolves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("sleepy", slog.Any("v", sleepyValuer{}))
+		}()
+	}
+	wg.Wait()
+
+	res := sink.Get()
+	assert.Equal(t, 3*MaxPendingResolves, strings.Count(res, `"v":"sleepy"`))
+	assert.NotContains(t, res, "TIMEOUT")
+}
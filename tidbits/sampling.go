@@ -0,0 +1,33 @@
+package tidbits
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Sampler decides whether the record is logged, it must be safe for concurrent use
+type Sampler interface {
+	Sample(ctx context.Context, record slog.Record) bool
+}
+
+// EveryNthSampler logs every Nth record at or below MaxLevel, the records above MaxLevel are
+// always logged
+type EveryNthSampler struct {
+	n        uint64
+	maxLevel slog.Level
+	counter  atomic.Uint64
+}
+
+var _ Sampler = &EveryNthSampler{}
+
+func NewEveryNthSampler(n int, maxLevel slog.Level) *EveryNthSampler {
+	return &EveryNthSampler{n: uint64(max(n, 1)), maxLevel: maxLevel}
+}
+
+func (e *EveryNthSampler) Sample(ctx context.Context, record slog.Record) bool {
+	if record.Level > e.maxLevel {
+		return true
+	}
+	return (e.counter.Add(1)-1)%e.n == 0
+}
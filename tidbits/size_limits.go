@@ -0,0 +1,389 @@
+package tidbits
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// SizeLimits limits the size of the record attributes, the zero values disable the limits.
+// The limits are applied to all the attributes except the stack trace, including the logger and
+// the extracted attributes.
+type SizeLimits struct {
+	// MaxStringLen is the maximum length of the string values in bytes, the longer strings are
+	// cut and get the "…(truncated N bytes)" marker. It also applies to the text of the errors and
+	// fmt.Stringers (they are replaced by the truncated strings), to []byte (the marker is appended
+	// to the truncated bytes), and to the other byte slices like json.RawMessage (they are replaced
+	// by the truncated strings, as the cut data is not valid anymore).
+	MaxStringLen int
+	// MaxElements is the maximum number of the slice, array and map elements. The longer slices
+	// are replaced by []any with the "…(truncated N elements)" marker as the last element, the
+	// maps are replaced by groups with the "…" attribute. The limits are also applied to the kept
+	// elements, the slices and maps with the changed elements are replaced the same way.
+	MaxElements int
+	// MaxGroupDepth is the maximum nesting of groups, the deeper groups are replaced by the
+	// "…(truncated group of N attrs)" strings. The top-level groups are at the depth of 1.
+	MaxGroupDepth int
+
+	// MaxRecordSize is the budget for the approximate serialized size of the attributes, the
+	// attributes with the lowest Priority are dropped until the record fits
+	MaxRecordSize int
+	// Priority of the top-level attribute, nil means that all the attributes have the same priority.
+	// The larger attributes are dropped first among the attributes with the same priority.
+	Priority func(a slog.Attr) int
+
+	// The number of times each limit has been applied
+	TruncatedStrings     atomic.Uint64
+	TruncatedCollections atomic.Uint64
+	TruncatedGroups      atomic.Uint64
+	DroppedAttrs         atomic.Uint64
+}
+
+// Apply the limits to the attributes, the slice is modified in place
+func (l *SizeLimits) limitAttrs(attrs []slog.Attr) []slog.Attr {
+	for i, a := range attrs {
+		attrs[i] = slog.Attr{Key: a.Key, Value: l.limitValue(a.Value, 0)}
+	}
+	if l.MaxRecordSize > 0 {
+		attrs = l.fitRecord(attrs)
+	}
+	return attrs
+}
+
+func (l *SizeLimits) limitValue(v slog.Value, depth int) slog.Value {
+	res, _ := l.limit(v, depth)
+	return res
+}
+
+// Apply the limits to the value, it returns false if the value is not changed
+func (l *SizeLimits) limit(v slog.Value, depth int) (slog.Value, bool) {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		if l.MaxStringLen > 0 && len(v.String()) > l.MaxStringLen {
+			l.TruncatedStrings.Add(1)
+			return slog.StringValue(truncateString(v.String(), l.MaxStringLen)), true
+		}
+	case slog.KindGroup:
+		group := v.Group()
+		if l.MaxGroupDepth > 0 && depth >= l.MaxGroupDepth {
+			l.TruncatedGroups.Add(1)
+			return slog.StringValue(fmt.Sprintf("…(truncated group of %d attrs)", len(group))), true
+		}
+		var limited []slog.Attr
+		for i, a := range group {
+			lv, changed := l.limit(a.Value, depth+1)
+			if changed && limited == nil {
+				limited = slices.Clone(group)
+			}
+			if limited != nil {
+				limited[i].Value = lv
+			}
+		}
+		if limited != nil {
+			return slog.GroupValue(limited...), true
+		}
+	case slog.KindAny:
+		return l.limitAny(v, depth)
+	}
+	return v, false
+}
+
+func (l *SizeLimits) limitAny(v slog.Value, depth int) (slog.Value, bool) {
+	if v.Any() == nil {
+		return v, false
+	}
+	rv := reflect.ValueOf(v.Any())
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		// The self-referential collections are left as is after this depth
+		if depth >= maxReflectDepth {
+			return v, false
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return l.limitBytes(v, rv)
+		}
+		return l.limitSlice(v, rv, depth)
+	case reflect.Map:
+		return l.limitMap(v, rv, depth)
+	}
+
+	if l.MaxStringLen <= 0 {
+		return v, false
+	}
+	var text string
+	switch t := v.Any().(type) {
+	case error:
+		text = t.Error()
+	case fmt.Stringer:
+		text = t.String()
+	default:
+		return v, false
+	}
+	if len(text) <= l.MaxStringLen {
+		return v, false
+	}
+	l.TruncatedStrings.Add(1)
+	return slog.StringValue(truncateString(text, l.MaxStringLen)), true
+}
+
+func (l *SizeLimits) limitBytes(v slog.Value, rv reflect.Value) (slog.Value, bool) {
+	if l.MaxStringLen <= 0 || rv.Len() <= l.MaxStringLen || rv.Kind() != reflect.Slice {
+		return v, false
+	}
+	l.TruncatedStrings.Add(1)
+	data := rv.Bytes()
+	truncated := truncateString(string(data), l.MaxStringLen)
+	if rv.Type() == reflect.TypeFor[[]byte]() {
+		return slog.AnyValue([]byte(truncated)), true
+	}
+	return slog.StringValue(truncated), true
+}
+
+func (l *SizeLimits) limitSlice(v slog.Value, rv reflect.Value, depth int) (slog.Value, bool) {
+	if l.MaxElements <= 0 && l.MaxStringLen <= 0 && l.MaxGroupDepth <= 0 {
+		return v, false
+	}
+
+	kept := rv.Len()
+	if l.MaxElements > 0 {
+		kept = min(kept, l.MaxElements)
+	}
+
+	// The slice is copied only if it's changed
+	var res []any
+	for i := 0; i < kept; i++ {
+		elem := rv.Index(i).Interface()
+		limited, changed := l.limitElement(elem, depth)
+		if changed && res == nil {
+			res = make([]any, 0, kept+1)
+			for j := 0; j < i; j++ {
+				res = append(res, rv.Index(j).Interface())
+			}
+		}
+		if res != nil {
+			res = append(res, limited)
+		}
+	}
+
+	if kept < rv.Len() {
+		l.TruncatedCollections.Add(1)
+		if res == nil {
+			res = make([]any, 0, kept+1)
+			for j := 0; j < kept; j++ {
+				res = append(res, rv.Index(j).Interface())
+			}
+		}
+		res = append(res, fmt.Sprintf("…(truncated %d elements)", rv.Len()-kept))
+	}
+
+	if res == nil {
+		return v, false
+	}
+	return slog.AnyValue(res), true
+}
+
+// Limit the slice element, the groups (from the limited maps) are turned back into maps
+func (l *SizeLimits) limitElement(elem any, depth int) (any, bool) {
+	limited, changed := l.limit(slog.AnyValue(elem), depth+1)
+	if !changed {
+		return elem, false
+	}
+	return valueToAny(limited), true
+}
+
+func valueToAny(v slog.Value) any {
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+	res := make(map[string]any, len(v.Group()))
+	for _, a := range v.Group() {
+		res[a.Key] = valueToAny(a.Value)
+	}
+	return res
+}
+
+func (l *SizeLimits) limitMap(v slog.Value, rv reflect.Value, depth int) (slog.Value, bool) {
+	if l.MaxElements <= 0 && l.MaxStringLen <= 0 && l.MaxGroupDepth <= 0 {
+		return v, false
+	}
+
+	truncated := l.MaxElements > 0 && rv.Len() > l.MaxElements
+	// MapIndex can't find the NaN keys, so the values are taken from the iterator
+	type entry struct {
+		name  string
+		value any
+	}
+	entries := make([]entry, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		entries = append(entries, entry{name: fmt.Sprint(iter.Key().Interface()), value: iter.Value().Interface()})
+	}
+	if truncated {
+		// Sort the keys to keep the same elements for the same map
+		slices.SortStableFunc(entries, func(a, b entry) int { return cmp.Compare(a.name, b.name) })
+		entries = entries[:l.MaxElements]
+	}
+
+	changed := truncated
+	res := make([]slog.Attr, 0, len(entries)+1)
+	for _, e := range entries {
+		elem, elemChanged := l.limit(slog.AnyValue(e.value), depth+1)
+		changed = changed || elemChanged
+		res = append(res, slog.Attr{Key: e.name, Value: elem})
+	}
+	if !changed {
+		return v, false
+	}
+
+	if truncated {
+		l.TruncatedCollections.Add(1)
+		res = append(res, slog.String("…", fmt.Sprintf("(truncated %d elements)", rv.Len()-l.MaxElements)))
+	} else {
+		slices.SortFunc(res, func(a, b slog.Attr) int { return cmp.Compare(a.Key, b.Key) })
+	}
+	return slog.GroupValue(res...), true
+}
+
+// Drop the lowest-priority attributes until the record fits into the budget
+func (l *SizeLimits) fitRecord(attrs []slog.Attr) []slog.Attr {
+	total := 0
+	sizes := make([]int, len(attrs))
+	for i, a := range attrs {
+		// The attribute larger than the whole budget is dropped anyway, so its size is not measured
+		sizes[i] = attrSize(a, l.MaxRecordSize+1)
+		total += sizes[i]
+	}
+	if total <= l.MaxRecordSize {
+		return attrs
+	}
+
+	// The candidates for dropping, the first one is dropped first
+	prio := make([]int, len(attrs))
+	order := make([]int, len(attrs))
+	for i, a := range attrs {
+		if l.Priority != nil {
+			prio[i] = l.Priority(a)
+		}
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Or(cmp.Compare(prio[a], prio[b]), cmp.Compare(sizes[b], sizes[a]), cmp.Compare(b, a))
+	})
+
+	dropped := make([]bool, len(attrs))
+	for _, i := range order {
+		if total <= l.MaxRecordSize {
+			break
+		}
+		dropped[i] = true
+		total -= sizes[i]
+		l.DroppedAttrs.Add(1)
+	}
+
+	res := attrs[:0]
+	for i, a := range attrs {
+		if !dropped[i] {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+// The approximate serialized size of the attribute, the estimation stops once the size reaches
+// the limit, so the result is at most a bit over the limit
+func attrSize(a slog.Attr, limit int) int {
+	return len(a.Key) + valueSize(a.Value, limit-len(a.Key))
+}
+
+func valueSize(v slog.Value, limit int) int {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return len(v.String())
+	case slog.KindGroup:
+		res := 0
+		for _, a := range v.Group() {
+			if res >= limit {
+				break
+			}
+			res += attrSize(a, limit-res)
+		}
+		return res
+	case slog.KindAny:
+		return reflectSize(reflect.ValueOf(v.Any()), limit, 0)
+	default:
+		return len(v.String())
+	}
+}
+
+// The size of the reflected value, without formatting it. The nested slices, maps, structs and
+// pointers are followed up to this depth, so the self-referential values are not traversed forever.
+const maxReflectDepth = 16
+
+func reflectSize(rv reflect.Value, limit int, depth int) int {
+	if !rv.IsValid() {
+		return 4 // null
+	}
+	if rv.CanInterface() {
+		switch t := rv.Interface().(type) {
+		case error:
+			return len(t.Error())
+		case fmt.Stringer:
+			return len(t.String())
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.Len()
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Pointer, reflect.Interface:
+		if depth >= maxReflectDepth {
+			return 0
+		}
+	default:
+		return 8
+	}
+
+	res := 0
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Len() * 4 / 3 // base64
+		}
+		for i := 0; i < rv.Len() && res < limit; i++ {
+			res += reflectSize(rv.Index(i), limit-res, depth+1) + 1
+		}
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() && res < limit {
+			res += reflectSize(iter.Key(), limit-res, depth+1) + reflectSize(iter.Value(), limit-res, depth+1) + 2
+		}
+	case reflect.Struct:
+		for i := 0; i < rv.NumField() && res < limit; i++ {
+			res += len(rv.Type().Field(i).Name) + reflectSize(rv.Field(i), limit-res, depth+1) + 2
+		}
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return 4
+		}
+		return reflectSize(rv.Elem(), limit, depth+1)
+	}
+	return res
+}
+
+// Cut the string at the rune boundary and add the truncation marker
+func truncateString(s string, maxLen int) string {
+	cut := maxLen
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("…(truncated %d bytes)", len(s)-cut)
+}
@@ -0,0 +1,119 @@
+package tidbits
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSizeLimits(t *testing.T) {
+	t.Parallel()
+
+	limits := &SizeLimits{MaxStringLen: 5, MaxElements: 2, MaxGroupDepth: 1}
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{Limits: limits}, sink.Handler()))
+
+	logger.Info("limited", slog.String("str", "héllo, world"),
+		slog.Any("slice", []int{1, 2, 3, 4}), slog.Any("map", map[string]int{"c": 3, "a": 1, "b": 2}),
+		slog.Group("g", slog.String("short", "ok"), slog.Group("nested", slog.Int("a", 1))),
+		slog.Any("bytes", []byte("abc")))
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"limited","str":"héll…(truncated 8 bytes)",`+
+		`"slice":[1,2,"…(truncated 2 elements)"],"map":{"a":1,"b":2,"…":"(truncated 1 elements)"},`+
+		`"g":{"short":"ok","nested":"…(truncated group of 1 attrs)"},"bytes":"YWJj"}`, sink.Get())
+	assert.EqualValues(t, 1, limits.TruncatedStrings.Load())
+	assert.EqualValues(t, 2, limits.TruncatedCollections.Load())
+	assert.EqualValues(t, 1, limits.TruncatedGroups.Load())
+}
+
+func TestRecordSizeBudget(t *testing.T) {
+	t.Parallel()
+
+	limits := &SizeLimits{
+		MaxRecordSize: 20,
+		Priority: func(a slog.Attr) int {
+			if a.Key == "id" {
+				return 1
+			}
+			return 0
+		},
+	}
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{Limits: limits}, sink.Handler()))
+
+	logger.Info("budget", slog.String("id", "42"), slog.String("body", strings.Repeat("x", 100)),
+		slog.String("a", "1"), slog.String("b", "2"))
+	logger.Info("fits", slog.String("a", "1"))
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"budget","id":"42","a":"1","b":"2"}
+{"time":"","level":"INFO","msg":"fits","a":"1"}`, sink.Get())
+	assert.EqualValues(t, 1, limits.DroppedAttrs.Load())
+}
+
+func TestSizeLimitsNestedAndText(t *testing.T) {
+	t.Parallel()
+
+	limits := &SizeLimits{MaxStringLen: 5}
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{Limits: limits}, sink.Handler()))
+
+	logger.Info("limited", slog.Any("bytes", []byte("abcdefgh")),
+		slog.Any("strs", []string{"ok", "too long"}), slog.Any("err", errors.New("failed badly")),
+		slog.Any("map", map[string]string{"k": "long value"}))
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"limited","bytes":"YWJjZGXigKYodHJ1bmNhdGVkIDMgYnl0ZXMp",`+
+		`"strs":["ok","too l…(truncated 3 bytes)"],"err":"faile…(truncated 7 bytes)",`+
+		`"map":{"k":"long …(truncated 5 bytes)"}}`, sink.Get())
+	assert.EqualValues(t, 4, limits.TruncatedStrings.Load())
+}
+
+func TestAttrSizeIsBounded(t *testing.T) {
+	t.Parallel()
+
+	huge := make([]string, 100000)
+	for i := range huge {
+		huge[i] = "some value"
+	}
+	assert.Less(t, attrSize(slog.Any("huge", huge), 100), 200)
+	assert.Equal(t, 7, attrSize(slog.String("key", "abcd"), 100))
+}
+
+func TestSizeLimitsNaNKeys(t *testing.T) {
+	t.Parallel()
+
+	limits := &SizeLimits{MaxStringLen: 5, MaxElements: 2}
+	sink := NewSinkingLogger(slog.LevelInfo)
+	logger := slog.New(NewSlogConvenience(SlogOptions{Limits: limits}, sink.Handler()))
+
+	logger.Info("nan", slog.Any("truncated", map[float64]int{math.NaN(): 1, 2: 2, 1: 3}),
+		slog.Any("limited", map[float64]string{math.NaN(): "long value"}))
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"nan","truncated":{"1":3,"2":2,"…":"(truncated 1 elements)"},`+
+		`"limited":{"NaN":"long …(truncated 5 bytes)"}}`, sink.Get())
+}
+
+type selfSlice struct {
+	C []selfSlice
+}
+
+type selfMap map[string]selfMap
+
+func TestSizeLimitsSelfReferential(t *testing.T) {
+	t.Parallel()
+
+	s := selfSlice{C: make([]selfSlice, 1)}
+	s.C[0] = s
+	m := selfMap{}
+	m["m"] = m
+	anys := []any{nil}
+	anys[0] = anys
+
+	limits := &SizeLimits{MaxElements: 2, MaxRecordSize: 1000}
+	for _, v := range []any{s, m, anys} {
+		assert.Less(t, attrSize(slog.Any("v", v), 1000), 1000)
+		limits.limitAttrs([]slog.Attr{slog.Any("v", v)})
+	}
+}
@@ -0,0 +1,161 @@
+// Package snapshot implements the golden-file testing for the log output. The volatile parts of
+// the log messages (times, source lines, goroutine IDs, tracing IDs, durations and stack locations)
+// are normalized, and the result is compared with the testdata/<name>.golden file. To rewrite the
+// golden files, run the tests with TIDBITS_SNAPSHOT_UPDATE=1, or with the `-update` flag. The flag
+// is not registered automatically, call RegisterUpdateFlag from the test package's init() or
+// TestMain to enable it:
+//
+//	func init() {
+//		snapshot.RegisterUpdateFlag()
+//	}
+package snapshot
+
+import (
+	"flag"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// UpdateEnvVar is the environment variable that enables the golden file updates
+const UpdateEnvVar = "TIDBITS_SNAPSHOT_UPDATE"
+
+// Check if the golden files should be rewritten, the flag is looked up lazily because
+// it's parsed after the initialization of the packages
+func shouldUpdate() bool {
+	if v, _ := strconv.ParseBool(os.Getenv(UpdateEnvVar)); v {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	v, _ := getter.Get().(bool)
+	return v
+}
+
+// RegisterUpdateFlag defines the `-update` flag in the global flag set. It does nothing if
+// the flag is already defined (by the test package or by another library), in this case
+// the existing flag is used if it's a boolean.
+func RegisterUpdateFlag() {
+	if flag.Lookup("update") != nil {
+		return
+	}
+	flag.Bool("update", false, "update the golden files for the log snapshots")
+}
+
+type rule struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// Normalizer replaces the volatile fields in the JSON and text log output
+type Normalizer struct {
+	rules []rule
+}
+
+// NewNormalizer creates the normalizer for the default set of fields, durationKeys are the
+// additional attribute names with durations (in the JSON output they are just numbers)
+func NewNormalizer(durationKeys ...string) *Normalizer {
+	n := &Normalizer{}
+
+	// Time
+	n.Add(`"time":"[^"]*"`, `"time":""`)
+	n.Add(`(^|\s)time=\S*`, `${1}time=""`)
+
+	// Source locations, only the file name is left
+	n.Add(`"file":"(?:[^"]*/)?([^"/]*)","line":\d+`, `"file":"$1","line":0`)
+	n.Add(`(^|\s)source=(?:\S*/)?([^\s/]+):\d+`, `${1}source=$2:0`)
+
+	// Goroutine IDs
+	n.Add(`"(goroutine|goroutine_id|goid|parent_id)":\d+`, `"$1":0`)
+	n.Add(`"id":\d+,"state":`, `"id":0,"state":`) // GoroutineDump
+	n.Add(`(^|\s)(goroutine|goroutine_id|goid)=\d+`, `${1}$2=0`)
+	n.Add(`goroutine \d+ \[`, `goroutine 0 [`)
+
+	// Tracing IDs
+	n.Add(`"(trace_id|span_id|traceparent)":"[^"]*"`, `"$1":"<$1>"`)
+	n.Add(`(^|\s)(trace_id|span_id|traceparent)=\S+`, `${1}$2=<$2>`)
+
+	// Durations in the text format and in the JSON strings
+	n.Add(`=(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+(\s|$)`, `=<duration>$4`)
+	n.Add(`":"(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+"`, `":"<duration>"`)
+	for _, k := range durationKeys {
+		n.Add(`"(`+regexp.QuoteMeta(k)+`)":\d+`, `"$1":"<duration>"`)
+	}
+
+	// Line numbers in the stack traces
+	n.Add(`"fl":"([^"]*):\d+"`, `"fl":"$1:0"`)
+	n.Add(`(\.go):\d+ `, `$1:0 `)
+
+	return n
+}
+
+// Add a custom normalization rule, the replacement can contain the regexp group references
+func (n *Normalizer) Add(re string, repl string) *Normalizer {
+	n.rules = append(n.rules, rule{re: regexp.MustCompile(re), repl: repl})
+	return n
+}
+
+func (n *Normalizer) Normalize(logs string) string {
+	lines := strings.Split(strings.TrimSpace(logs), "\n")
+	for i, ln := range lines {
+		for _, r := range n.rules {
+			ln = r.re.ReplaceAllString(ln, r.repl)
+		}
+		lines[i] = ln
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+var defaultNormalizer = NewNormalizer()
+
+// Normalize the logs with the default normalizer
+func Normalize(logs string) string {
+	return defaultNormalizer.Normalize(logs)
+}
+
+// Match compares the normalized logs with the testdata/<name>.golden file
+func Match(t testing.TB, name string, logs string) bool {
+	t.Helper()
+	return MatchWith(t, defaultNormalizer, name, logs)
+}
+
+// MatchSink compares the accumulated logs from the SinkingLogger with the golden file,
+// the sink is reset after that
+func MatchSink(t testing.TB, name string, sink *tidbits.SinkingLogger) bool {
+	t.Helper()
+	return Match(t, name, sink.Get())
+}
+
+func MatchWith(t testing.TB, n *Normalizer, name string, logs string) bool {
+	t.Helper()
+
+	normalized := n.Normalize(logs)
+	goldenPath := filepath.Join("testdata", name+".golden")
+
+	if shouldUpdate() {
+		err := os.MkdirAll(filepath.Dir(goldenPath), 0755)
+		if err == nil {
+			err = os.WriteFile(goldenPath, []byte(normalized), 0644)
+		}
+		return assert.NoError(t, err, "failed to update the golden file")
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Errorf("Failed to read the golden file %s (run the test with %s=1 to create it): %v",
+			goldenPath, UpdateEnvVar, err)
+		return false
+	}
+	return assert.Equal(t, string(expected), normalized, "the logs don't match %s", goldenPath)
+}
@@ -0,0 +1,69 @@
+package snapshot
+
+import (
+	"bytes"
+	"flag"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func init() {
+	RegisterUpdateFlag()
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	logs := `{"time":"2024-08-18T12:38:47.271-07:00","level":"INFO","source":{"function":"main.main","file":"/home/user/main.go","line":16},"msg":"hi","goroutine":18,"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","took":"1.5s"}
+time=2024-08-18T12:38:47.271-07:00 level=INFO source=/home/user/main.go:16 msg=hi span_id=00f067aa0ba902b7 took=150ms
+goroutine 18 [chan receive]:`
+
+	assert.Equal(t, `{"time":"","level":"INFO","source":{"function":"main.main","file":"main.go","line":0},"msg":"hi","goroutine":0,"trace_id":"<trace_id>","took":"<duration>"}
+time="" level=INFO source=main.go:0 msg=hi span_id=<span_id> took=<duration>
+goroutine 0 [chan receive]:
+`, Normalize(logs))
+
+	n := NewNormalizer("elapsed")
+	assert.Equal(t, `{"elapsed":"<duration>","count":12}`+"\n", n.Normalize(`{"elapsed":123456,"count":12}`))
+}
+
+func TestSnapshotJSON(t *testing.T) {
+	t.Parallel()
+
+	sink := tidbits.NewSinkingLogger(slog.LevelInfo)
+	sink.Info("request served", slog.String("path", "/api"), slog.Int("status", 200),
+		slog.String("took", (1500*time.Millisecond).String()))
+	sink.Error("request failed", tidbits.StackTraceAttrWithOptions(
+		tidbits.StackOptions{SkipTesting: true}, "boom"))
+	MatchSink(t, "json", sink)
+}
+
+func TestSnapshotText(t *testing.T) {
+	t.Parallel()
+
+	data := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(data, &slog.HandlerOptions{AddSource: true}))
+	logger.Info("request served", slog.String("path", "/api"), slog.Duration("took", 1500*time.Millisecond))
+	Match(t, "text", data.String())
+}
+
+func TestShouldUpdate(t *testing.T) {
+	// Registering the flag twice is fine
+	assert.NotPanics(t, RegisterUpdateFlag)
+
+	prev := flag.Lookup("update").Value.String()
+	defer func() { _ = flag.Set("update", prev) }()
+
+	t.Setenv(UpdateEnvVar, "")
+	assert.NoError(t, flag.Set("update", "false"))
+	assert.False(t, shouldUpdate())
+	assert.NoError(t, flag.Set("update", "true"))
+	assert.True(t, shouldUpdate())
+
+	assert.NoError(t, flag.Set("update", "false"))
+	t.Setenv(UpdateEnvVar, "1")
+	assert.True(t, shouldUpdate())
+}
@@ -0,0 +1,89 @@
+package tidbits
+
+import (
+	"container/list"
+	"encoding/hex"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const StackIDAttrName = "stack_id"
+
+// Fingerprint returns a stable hash of the stack frames (after filtering), the panic message is
+// not included. If withLines is false, the line numbers are ignored, so the fingerprint survives
+// unrelated code edits.
+func (s *StackValue) Fingerprint(withLines bool) string {
+	h := fnv.New64a()
+	for _, e := range s.frames() {
+		fl := e.Fl
+		if !withLines {
+			if idx := strings.LastIndex(fl, ":"); idx != -1 {
+				fl = fl[:idx]
+			}
+		}
+		_, _ = h.Write([]byte(fl))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(e.Fn))
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MaxDedupStacks is the number of the stack fingerprints remembered by StackDeduplicator, the least
+// recently seen fingerprints are forgotten first
+const MaxDedupStacks = 1024
+
+// StackDeduplicator tracks the stack fingerprints that have been logged recently. It's
+// used by SlogConvenience to emit the full stack only the first time it's seen within the window.
+type StackDeduplicator struct {
+	mtx      sync.Mutex
+	window   time.Duration
+	lastSeen map[string]*list.Element
+	// The entries ordered from the most recently seen to the least recently seen
+	lru *list.List
+}
+
+type dedupEntry struct {
+	stackID  string
+	lastEmit time.Time
+}
+
+func NewStackDeduplicator(window time.Duration) *StackDeduplicator {
+	return &StackDeduplicator{
+		window:   window,
+		lastSeen: make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// ShouldEmit returns true if the stack with this ID has not been emitted within the window
+// before the specified time, and remembers the emission. The zero time means the current time.
+func (d *StackDeduplicator) ShouldEmit(stackID string, now time.Time) bool {
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	elem, ok := d.lastSeen[stackID]
+	if ok {
+		d.lru.MoveToFront(elem)
+		entry := elem.Value.(*dedupEntry)
+		if now.Sub(entry.lastEmit) < d.window {
+			return false
+		}
+		entry.lastEmit = now
+		return true
+	}
+
+	d.lastSeen[stackID] = d.lru.PushFront(&dedupEntry{stackID: stackID, lastEmit: now})
+	if d.lru.Len() > MaxDedupStacks {
+		oldest := d.lru.Back()
+		d.lru.Remove(oldest)
+		delete(d.lastSeen, oldest.Value.(*dedupEntry).stackID)
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package tidbits
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStackDeduplicatorWindow(t *testing.T) {
+	t.Parallel()
+
+	d := NewStackDeduplicator(time.Minute)
+	start := time.Unix(1000, 0)
+	assert.True(t, d.ShouldEmit("a", start))
+	assert.False(t, d.ShouldEmit("a", start.Add(30*time.Second)))
+	assert.True(t, d.ShouldEmit("a", start.Add(time.Minute)))
+
+	// The zero time is the current time, so the window expires
+	d = NewStackDeduplicator(time.Millisecond)
+	assert.True(t, d.ShouldEmit("a", time.Time{}))
+	time.Sleep(2 * time.Millisecond)
+	assert.True(t, d.ShouldEmit("a", time.Time{}))
+}
+
+func TestStackDeduplicatorCapacity(t *testing.T) {
+	t.Parallel()
+
+	d := NewStackDeduplicator(time.Hour)
+	now := time.Unix(1000, 0)
+	for i := 0; i < MaxDedupStacks*2; i++ {
+		assert.True(t, d.ShouldEmit(strconv.Itoa(i), now))
+	}
+	assert.Equal(t, MaxDedupStacks, len(d.lastSeen))
+	assert.Equal(t, MaxDedupStacks, d.lru.Len())
+
+	// The oldest fingerprints are forgotten
+	assert.False(t, d.ShouldEmit(strconv.Itoa(MaxDedupStacks*2-1), now))
+	assert.True(t, d.ShouldEmit("0", now))
+}
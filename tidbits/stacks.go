@@ -10,14 +10,43 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const StackAttrName = "stack"
 
+// StackOptions controls the capture and rendering of stack traces. The filters are applied
+// identically by both MarshalText and JSONStack.
+type StackOptions struct {
+	// SkipToFirstPanic skips all the deferred frames after the first panic() call
+	SkipToFirstPanic bool
+	// MaxDepth limits the number of rendered frames, zero means no limit
+	MaxDepth int
+	// SkipRuntime drops the frames from the `runtime` package
+	SkipRuntime bool
+	// SkipTesting drops the frames from the `testing` package
+	SkipTesting bool
+	// SkipPrefixes drops the frames with the function names starting with any of these prefixes
+	// (e.g. "github.com/stretchr/testify/")
+	SkipPrefixes []string
+	// CollapseRecursion replaces runs of consecutive frames from the same function with
+	// a single frame that has the repetition counter
+	CollapseRecursion bool
+	// OwnPrefix marks the first frame with the function name starting with this prefix,
+	// it's typically the module name (e.g. "github.com/Cyberax/slog-tidbits/")
+	OwnPrefix string
+}
+
 type StackValue struct {
-	skipToFirstPanic bool
-	stack            []uintptr
-	msg              string
+	opts  StackOptions
+	stack []uintptr
+	msg   string
+	// The frames parsed from a traceback text, used instead of the captured stack
+	parsed []symbolFrame
+
+	// The stack is rendered lazily, exactly once
+	renderOnce sync.Once
+	rendered   []StackElement
 }
 
 var _ json.Marshaler = &StackValue{}
@@ -28,11 +57,28 @@ func StackTraceAttr(skipToFirstPanic bool, msg string) slog.Attr {
 	return slog.Any(StackAttrName, NewStackValue(2, skipToFirstPanic, msg))
 }
 
+func StackTraceAttrWithOptions(opts StackOptions, msg string) slog.Attr {
+	return slog.Any(StackAttrName, NewStackValueWithOptions(2, opts, msg))
+}
+
 func NewStackValue(skipFrames int, skipToFirstPanic bool, msg any) *StackValue {
-	stack := make([]uintptr, 128)
-	num := runtime.Callers(skipFrames, stack)
+	return NewStackValueWithOptions(skipFrames+1, StackOptions{SkipToFirstPanic: skipToFirstPanic}, msg)
+}
 
-	return &StackValue{skipToFirstPanic: skipToFirstPanic, stack: stack[:num], msg: PanicMsgToString(msg)}
+func NewStackValueWithOptions(skipFrames int, opts StackOptions, msg any) *StackValue {
+	return &StackValue{opts: opts, stack: captureStack(skipFrames + 1), msg: PanicMsgToString(msg)}
+}
+
+// Capture the full stack, growing the buffer if the stack doesn't fit into it
+func captureStack(skipFrames int) []uintptr {
+	stack := make([]uintptr, 128)
+	for {
+		num := runtime.Callers(skipFrames, stack)
+		if num < len(stack) {
+			return stack[:num]
+		}
+		stack = make([]uintptr, len(stack)*2)
+	}
 }
 
 func PanicMsgToString(msg interface{}) string {
@@ -50,18 +96,51 @@ func PanicMsgToString(msg interface{}) string {
 	return reflect.ValueOf(msg).String()
 }
 
+// LogValue returns the value that is marshalled into a structured list by the JSON handlers
+// and into a multi-line string by the text handlers.
 func (s *StackValue) LogValue() slog.Value {
-	text, err := s.MarshalText()
-	if err != nil {
-		return slog.StringValue("Failed to marshal the stack: " + string(text))
-	}
-	return slog.StringValue(string(text))
+	return slog.AnyValue((*stackView)(s))
+}
+
+// stackView is a StackValue without the LogValue method, so it's not resolved recursively
+type stackView StackValue
+
+func (v *stackView) MarshalJSON() ([]byte, error) {
+	return (*StackValue)(v).MarshalJSON()
+}
+
+func (v *stackView) MarshalText() ([]byte, error) {
+	return (*StackValue)(v).MarshalText()
 }
 
 type StackElement struct {
 	Msg string `json:"panic_msg,omitempty"`
 	Fl  string `json:"fl,omitempty"`
 	Fn  string `json:"fn,omitempty"`
+	// Rep is the number of collapsed recursive calls
+	Rep int `json:"rep,omitempty"`
+	// Own is set for the first frame within the StackOptions.OwnPrefix
+	Own bool `json:"own,omitempty"`
+}
+
+// Format the frame as a single line of text
+func (e StackElement) String() string {
+	var res strings.Builder
+	e.writeText(&res)
+	return res.String()
+}
+
+func (e StackElement) writeText(res *strings.Builder) {
+	res.WriteString(e.Fl)
+	res.WriteByte(' ')
+	res.WriteString(e.Fn)
+	if e.Rep > 1 {
+		res.WriteString(" x")
+		res.WriteString(strconv.Itoa(e.Rep))
+	}
+	if e.Own {
+		res.WriteString(" <--")
+	}
 }
 
 func (s *StackValue) MarshalJSON() ([]byte, error) {
@@ -71,103 +150,199 @@ func (s *StackValue) MarshalJSON() ([]byte, error) {
 // JSONStack creates a nice stack trace, skipping all the deferred frames after the first panic() call.
 // This method returns the list of structures that can be nicely reflected into JSON.
 func (s *StackValue) JSONStack() []StackElement {
-	// Create the stack trace
-	stackElements := make([]StackElement, 0, 20)
+	frames := s.frames()
+	stackElements := make([]StackElement, 0, len(frames)+1)
 	stackElements = append(stackElements, StackElement{Msg: s.msg})
+	return append(stackElements, frames...)
+}
 
-	panicsToSkip := 0
-	if s.skipToFirstPanic {
-		panicsToSkip = s.countPanics()
+// MarshalText creates a nice stack trace, skipping all the deferred frames after the first panic() call.
+// This method returns a human-readable multi-line string.
+func (s *StackValue) MarshalText() (text []byte, err error) {
+	frames := s.frames()
+
+	var res strings.Builder
+	res.Grow(len(s.msg) + 1 + len(frames)*80)
+	res.WriteString(s.msg)
+	res.WriteByte('\n')
+	for _, e := range frames {
+		e.writeText(&res)
+		res.WriteByte('\n')
 	}
+	return []byte(res.String()), nil
+}
 
-	// Note: On the last iteration, frames.Next() returns false, with a valid
-	// frame, but we ignore this frame. The last frame is the runtime frame which
-	// adds noise, since it always starts in the runtime.
-	frames := runtime.CallersFrames(s.stack)
-	for frame, more := frames.Next(); more; frame, more = frames.Next() {
-		filePath, line, label := s.parseFrame(frame)
+// Get the filtered stack frames, they are computed once and then reused by all the renderers
+func (s *StackValue) frames() []StackElement {
+	s.renderOnce.Do(func() {
+		s.rendered = s.renderFrames()
+	})
+	return s.rendered
+}
 
-		if panicsToSkip > 0 && strings.HasPrefix(filePath, "runtime/panic") && label == "gopanic" {
-			panicsToSkip -= 1
-			continue
-		}
-		if panicsToSkip > 0 {
-			continue
+// Walk the stack frames, applying the filtering options
+func (s *StackValue) renderFrames() []StackElement {
+	symbolized := s.parsed
+	if symbolized == nil {
+		symbolized = symbolizeStack(s.stack)
+		// Note: The last frame is the runtime frame which adds noise, since it always
+		// starts in the runtime. So we ignore it.
+		if len(symbolized) > 0 {
+			symbolized = symbolized[:len(symbolized)-1]
 		}
-
-		stackElements = append(stackElements, StackElement{
-			Fl: filePath + ":" + strconv.Itoa(line),
-			Fn: label,
-		})
 	}
-	return stackElements
-}
-
-// MarshalText creates a nice stack trace, skipping all the deferred frames after the first panic() call.
-// This method returns a human-readable multi-line string.
-func (s *StackValue) MarshalText() (text []byte, err error) {
-	// Create the stack trace
-	frames := runtime.CallersFrames(s.stack)
 
 	panicsToSkip := 0
-	if s.skipToFirstPanic {
-		panicsToSkip = s.countPanics()
+	if s.opts.SkipToFirstPanic {
+		panicsToSkip = countPanics(symbolized)
 	}
 
-	var res string
-	res += s.msg + "\n"
+	res := make([]StackElement, 0, len(symbolized))
+	ownFound := false
+	lastFilePath := ""
 
-	// Note: On the last iteration, frames.Next() returns false, with a valid
-	// frame, but we ignore this frame. The last frame is the runtime frame which
-	// adds noise, since it always starts in the runtime.
-	for frame, more := frames.Next(); more; frame, more = frames.Next() {
-		filePath, line, label := s.parseFrame(frame)
+	for i := range symbolized {
+		frame := &symbolized[i]
 
-		if panicsToSkip > 0 && strings.HasPrefix(filePath, "runtime/panic") && label == "gopanic" {
+		if panicsToSkip > 0 && frame.isPanic {
 			panicsToSkip -= 1
 			continue
 		}
 		if panicsToSkip > 0 {
 			continue
 		}
+		if s.skipFunction(frame.function) {
+			continue
+		}
+
+		if s.opts.CollapseRecursion && len(res) > 0 {
+			last := &res[len(res)-1]
+			if last.Fn == frame.elem.Fn && lastFilePath == frame.filePath {
+				last.Rep = max(last.Rep, 1) + 1
+				continue
+			}
+		}
 
-		res += filePath + ":" + strconv.Itoa(line) + " " + label + "\n"
+		if s.opts.MaxDepth > 0 && len(res) >= s.opts.MaxDepth {
+			break
+		}
+
+		elem := frame.elem
+		if !ownFound && s.opts.OwnPrefix != "" && strings.HasPrefix(frame.function, s.opts.OwnPrefix) {
+			elem.Own = true
+			ownFound = true
+		}
+		res = append(res, elem)
+		lastFilePath = frame.filePath
 	}
 
-	return []byte(res), nil
+	return res
+}
+
+func (s *StackValue) skipFunction(funcName string) bool {
+	if s.opts.SkipRuntime && strings.HasPrefix(funcName, "runtime.") {
+		return true
+	}
+	if s.opts.SkipTesting && strings.HasPrefix(funcName, "testing.") {
+		return true
+	}
+	for _, prefix := range s.opts.SkipPrefixes {
+		if strings.HasPrefix(funcName, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // The default stack trace contains the build environment full path as the first part of the file name.
 // This adds no information to the stack trace and exposes the building environment,
 // so process the stack trace to remove the building environment path.
-func (s *StackValue) parseFrame(frame runtime.Frame) (string, int, string) {
+func parseFrame(frame runtime.Frame) symbolFrame {
+	packagePath, funcName := shortenLocation(frame.Function, frame.File)
+	return symbolFrame{
+		function: frame.Function,
+		filePath: packagePath,
+		isPanic:  strings.HasPrefix(packagePath, "runtime/panic") && funcName == "gopanic",
+		elem: StackElement{
+			Fl: packagePath + ":" + strconv.Itoa(frame.Line),
+			Fn: funcName,
+		},
+	}
+}
+
+// shortenLocation turns the fully-qualified function name and the file path into the package-relative
+// file path and the short function name.
+func shortenLocation(function, file string) (string, string) {
 	// Example:
-	// frame.Function = github.com/Cyberax/slog-tidbits/tidbits.StackTraceAttr
-	// frame.Line = 18
-	// frame.File = /Users/cyberax/bricks/slog-tidbits/tidbits/stacks.go
-	fname := path.Base(frame.File)
-	dotIdx := strings.LastIndex(frame.Function, ".")
+	// function = github.com/Cyberax/slog-tidbits/tidbits.StackTraceAttr
+	// file = /Users/cyberax/bricks/slog-tidbits/tidbits/stacks.go
+	fname := path.Base(file)
+	dotIdx := strings.LastIndex(function, ".")
 
 	packagePath := fname
-	funcName := frame.Function
+	funcName := function
 	if dotIdx != -1 {
-		packagePath = frame.Function[:dotIdx] + "/" + fname
-		funcName = frame.Function[dotIdx+1:]
+		packagePath = function[:dotIdx] + "/" + fname
+		funcName = function[dotIdx+1:]
 	}
 
-	// github.com/Cyberax/slog-tidbits/tidbits/stacks.go, 11, NewStackValue
-	return packagePath, frame.Line, funcName
+	// github.com/Cyberax/slog-tidbits/tidbits/stacks.go, NewStackValue
+	return packagePath, funcName
 }
 
 // Count the number of go panic() calls in the stack trace
-func (s *StackValue) countPanics() int {
-	frames := runtime.CallersFrames(s.stack)
+func countPanics(frames []symbolFrame) int {
 	panics := 0
-	for frame, more := frames.Next(); more; frame, more = frames.Next() {
-		filePath, _, label := s.parseFrame(frame)
-		if strings.HasPrefix(filePath, "runtime/panic") && label == "gopanic" {
+	for i := range frames {
+		if frames[i].isPanic {
 			panics += 1
 		}
 	}
 	return panics
 }
+
+// symbolFrame is a stack frame with the precomputed presentation
+type symbolFrame struct {
+	function string
+	filePath string
+	isPanic  bool
+	elem     StackElement
+}
+
+// frameCache is the process-wide cache of the symbolized frames, keyed by PC. One PC can
+// correspond to multiple frames because of inlining. The number of PCs is bounded by the size
+// of the program code, so the cache doesn't need eviction.
+var frameCache sync.Map // uintptr -> []symbolFrame
+
+func symbolizeStack(stack []uintptr) []symbolFrame {
+	res := make([]symbolFrame, 0, len(stack)+len(stack)/4)
+	for i, pc := range stack {
+		if i > 0 && len(res) > 0 && res[len(res)-1].function == "runtime.sigpanic" {
+			// The PC after sigpanic is the faulting instruction rather than the return
+			// address, so it needs the previous frame for the correct symbolization
+			frames := runtime.CallersFrames(stack[i-1 : i+1])
+			_, _ = frames.Next() // Skip the sigpanic itself, it's never inlined
+			res = appendFrames(res, frames)
+			continue
+		}
+
+		cached, ok := frameCache.Load(pc)
+		if !ok {
+			cached, _ = frameCache.LoadOrStore(pc, appendFrames(nil, runtime.CallersFrames([]uintptr{pc})))
+		}
+		res = append(res, cached.([]symbolFrame)...)
+	}
+	return res
+}
+
+func appendFrames(res []symbolFrame, frames *runtime.Frames) []symbolFrame {
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" || frame.File != "" {
+			res = append(res, parseFrame(frame))
+		}
+		if !more {
+			return res
+		}
+	}
+}
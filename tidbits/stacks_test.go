@@ -14,8 +14,123 @@ func TestStackAttr(t *testing.T) {
 	val := sink.Get()
 	// This test is a bit brittle, because the line numbers can change
 	expected := `{"time":"","level":"ERROR","msg":"badmsg","stack":[{"panic_msg":"test panic"},
-{"fl":"github.com/Cyberax/slog-tidbits/tidbits/stacks.go:27","fn":"StackTraceAttr"},
+{"fl":"github.com/Cyberax/slog-tidbits/tidbits/stacks.go:57","fn":"StackTraceAttr"},
 {"fl":"github.com/Cyberax/slog-tidbits/tidbits/stacks_test.go:12","fn":"TestStackAttr"},
-{"fl":"testing/testing.go:1689","fn":"tRunner"}]}`
+{"fl":"testing/testing.go:2193","fn":"tRunner"}]}`
 	assert.Equal(t, strings.ReplaceAll(expected, "\n", ""), val)
 }
+
+func recurse(depth int, opts StackOptions) *StackValue {
+	if depth == 0 {
+		return NewStackValueWithOptions(2, opts, "deep")
+	}
+	return recurse(depth-1, opts)
+}
+
+func TestStackOptions(t *testing.T) {
+	t.Parallel()
+
+	// The stack is deeper than the initial buffer size
+	sv := recurse(200, StackOptions{})
+	assert.Greater(t, len(sv.JSONStack()), 200)
+
+	sv = recurse(200, StackOptions{
+		SkipTesting:       true,
+		SkipRuntime:       true,
+		CollapseRecursion: true,
+		OwnPrefix:         "github.com/Cyberax/slog-tidbits/tidbits.Test",
+	})
+	stack := sv.JSONStack()
+	assert.Equal(t, 3, len(stack))
+	assert.Equal(t, "deep", stack[0].Msg)
+	assert.Equal(t, "recurse", stack[1].Fn)
+	assert.Equal(t, 201, stack[1].Rep)
+	assert.Equal(t, "TestStackOptions", stack[2].Fn)
+	assert.True(t, stack[2].Own)
+
+	text, err := sv.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, `deep
+github.com/Cyberax/slog-tidbits/tidbits/stacks_test.go:25 recurse x201
+github.com/Cyberax/slog-tidbits/tidbits/stacks_test.go:37 TestStackOptions <--
+`, string(text))
+
+	sv = recurse(200, StackOptions{MaxDepth: 5,
+		SkipPrefixes: []string{"github.com/Cyberax/slog-tidbits/tidbits.recurse"}})
+	stack = sv.JSONStack()
+	assert.Equal(t, 3, len(stack))
+	assert.Equal(t, "TestStackOptions", stack[1].Fn)
+	assert.Equal(t, "tRunner", stack[2].Fn)
+}
+
+func TestStackFingerprint(t *testing.T) {
+	t.Parallel()
+
+	sv1 := NewStackValueWithOptions(1, StackOptions{}, "one")
+	sv2 := NewStackValueWithOptions(1, StackOptions{}, "two")
+
+	// Different lines within the same function
+	assert.Equal(t, sv1.Fingerprint(false), sv2.Fingerprint(false))
+	assert.NotEqual(t, sv1.Fingerprint(true), sv2.Fingerprint(true))
+	assert.Equal(t, 16, len(sv1.Fingerprint(true)))
+
+	sv3 := recurse(1, StackOptions{})
+	assert.NotEqual(t, sv1.Fingerprint(false), sv3.Fingerprint(false))
+}
+
+func typicalStack(depth int, opts StackOptions) *StackValue {
+	if depth == 0 {
+		return NewStackValueWithOptions(2, opts, "benchmark")
+	}
+	return typicalStack(depth-1, opts)
+}
+
+func TestStackRenderedOnce(t *testing.T) {
+	t.Parallel()
+
+	sv := typicalStack(10, StackOptions{})
+	stack := sv.JSONStack()
+	text, err := sv.MarshalText()
+	assert.NoError(t, err)
+	// The frames are shared between the renderers
+	assert.Same(t, &sv.frames()[0], &sv.frames()[0])
+	assert.Equal(t, len(stack), strings.Count(string(text), "\n"))
+}
+
+// The depth is chosen to get a typical 30-frame stack, including the testing frames
+func BenchmarkStackCapture(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		typicalStack(27, StackOptions{})
+	}
+}
+
+func BenchmarkStackMarshalText(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sv := typicalStack(27, StackOptions{})
+		_, _ = sv.MarshalText()
+	}
+}
+
+func BenchmarkStackMarshalJSON(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sv := typicalStack(27, StackOptions{})
+		_, _ = sv.MarshalJSON()
+	}
+}
+
+// Render the same stack through multiple sinks
+func BenchmarkStackFanOut(b *testing.B) {
+	b.ReportAllocs()
+	sinks := []*SinkingLogger{NewSinkingLogger(slog.LevelInfo),
+		NewJsonOrTextSinkingLogger(slog.LevelInfo, true), NewSinkingLogger(slog.LevelInfo)}
+	for i := 0; i < b.N; i++ {
+		attr := slog.Any(StackAttrName, typicalStack(27, StackOptions{}))
+		for _, s := range sinks {
+			s.Error("failure", attr)
+			s.Get()
+		}
+	}
+}
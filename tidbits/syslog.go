@@ -0,0 +1,311 @@
+package tidbits
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SyslogFacilityUser   = 1
+	SyslogFacilityDaemon = 3
+	SyslogFacilityLocal0 = 16
+)
+
+// The default SD-ID uses the private enterprise number reserved for documentation (RFC 5612)
+const DefaultSyslogSDID = "slog@32473"
+
+type SyslogOptions struct {
+	Level    slog.Leveler
+	Facility int
+	// The header fields, they default to the process name and the host name
+	AppName  string
+	Hostname string
+	MsgID    string
+	// SDID is the ID of the structured data element with the attributes
+	SDID string
+}
+
+// SyslogHandler formats the records as RFC 5424 messages, the attributes are written into
+// a STRUCTURED-DATA element with the dotted names for the groups.
+type SyslogHandler struct {
+	opts SyslogOptions
+	out  *syslogWriter
+
+	prefix    string
+	preformed []byte
+}
+
+var _ slog.Handler = &SyslogHandler{}
+
+// DialSyslog connects to the syslog server, the network can be "udp", "tcp", "unix" or "unixgram".
+// The stream-based connections use the octet-counting framing (RFC 6587).
+func DialSyslog(network, addr string, opts *SyslogOptions) (*SyslogHandler, error) {
+	w := &syslogWriter{network: network, addr: addr, framed: network == "tcp" || network == "unix"}
+	err := w.connect()
+	if err != nil {
+		return nil, err
+	}
+	return newSyslogHandler(w, opts), nil
+}
+
+// NewSyslogHandler creates the handler that writes the messages into the writer, with the
+// octet-counting framing if framed is true
+func NewSyslogHandler(out io.Writer, framed bool, opts *SyslogOptions) *SyslogHandler {
+	return newSyslogHandler(&syslogWriter{conn: out, framed: framed}, opts)
+}
+
+func newSyslogHandler(w *syslogWriter, opts *SyslogOptions) *SyslogHandler {
+	h := &SyslogHandler{out: w}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelInfo
+	}
+	if h.opts.Facility == 0 {
+		h.opts.Facility = SyslogFacilityUser
+	}
+	if h.opts.AppName == "" {
+		h.opts.AppName = filepath.Base(os.Args[0])
+	}
+	if h.opts.Hostname == "" {
+		h.opts.Hostname, _ = os.Hostname()
+	}
+	if h.opts.SDID == "" {
+		h.opts.SDID = DefaultSyslogSDID
+	}
+	return h
+}
+
+// Close closes the connection to the syslog server
+func (h *SyslogHandler) Close() error {
+	return h.out.close()
+}
+
+// SyslogSeverity maps the slog level to the syslog severity
+func SyslogSeverity(lvl slog.Level) int {
+	switch {
+	case lvl < slog.LevelInfo:
+		return 7 // Debug
+	case lvl < slog.LevelWarn:
+		return 6 // Informational
+	case lvl < slog.LevelError:
+		return 4 // Warning
+	case lvl < slog.LevelError+4:
+		return 3 // Error
+	default:
+		return 2 // Critical
+	}
+}
+
+func (h *SyslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+// Handle writes the message: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ELEMENT] MSG
+func (h *SyslogHandler) Handle(ctx context.Context, record slog.Record) error {
+	buf := bytes.NewBuffer(make([]byte, 0, 256))
+
+	buf.WriteByte('<')
+	buf.WriteString(strconv.Itoa(h.opts.Facility*8 + SyslogSeverity(record.Level)))
+	buf.WriteString(">1 ")
+	if record.Time.IsZero() {
+		buf.WriteString("-")
+	} else {
+		buf.WriteString(record.Time.Format("2006-01-02T15:04:05.000000Z07:00"))
+	}
+	for _, field := range []struct {
+		val    string
+		maxLen int
+	}{{h.opts.Hostname, 255}, {h.opts.AppName, 48}, {strconv.Itoa(os.Getpid()), 128}, {h.opts.MsgID, 32}} {
+		buf.WriteByte(' ')
+		buf.WriteString(syslogHeaderField(field.val, field.maxLen))
+	}
+	buf.WriteByte(' ')
+
+	params := bytes.NewBuffer(slices.Clone(h.preformed))
+	record.Attrs(func(a slog.Attr) bool {
+		h.appendParam(params, h.prefix, a)
+		return true
+	})
+	if params.Len() == 0 {
+		buf.WriteString("-")
+	} else {
+		buf.WriteString("[" + h.opts.SDID)
+		buf.Write(params.Bytes())
+		buf.WriteString("]")
+	}
+
+	if record.Message != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(record.Message)
+	}
+
+	return h.out.write(buf.Bytes())
+}
+
+func (h *SyslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	buf := bytes.NewBuffer(slices.Clone(h.preformed))
+	for _, a := range attrs {
+		h.appendParam(buf, h.prefix, a)
+	}
+	res := *h
+	res.preformed = buf.Bytes()
+	return &res
+}
+
+func (h *SyslogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	res := *h
+	res.prefix = h.prefix + name + "."
+	return &res
+}
+
+// Append the SD-PARAM: ` name="value"`
+func (h *SyslogHandler) appendParam(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	var val string
+	if sv, ok := a.Value.Any().(*StackValue); ok && a.Value.Kind() == slog.KindLogValuer {
+		text, _ := sv.MarshalText()
+		val = strings.TrimSuffix(string(text), "\n")
+	} else {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		if a.Value.Kind() == slog.KindGroup {
+			groupPrefix := prefix
+			if a.Key != "" {
+				groupPrefix += a.Key + "."
+			}
+			for _, ga := range a.Value.Group() {
+				h.appendParam(buf, groupPrefix, ga)
+			}
+			return
+		}
+		val = formatLogfmtValue(a.Value)
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(syslogParamName(prefix + a.Key))
+	buf.WriteString(`="`)
+	for _, r := range val {
+		// PARAM-VALUE must have '"', '\' and ']' escaped
+		if r == '"' || r == '\\' || r == ']' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(r)
+	}
+	buf.WriteByte('"')
+}
+
+// The SD-NAME is limited to 32 printable ASCII characters, except '=', ' ', ']' and '"'
+func syslogParamName(name string) string {
+	res := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(res) > 32 {
+		res = res[:32]
+	}
+	if res == "" {
+		return "_"
+	}
+	return res
+}
+
+// The header fields are printable ASCII without spaces, the empty values are replaced by NILVALUE
+func syslogHeaderField(val string, maxLen int) string {
+	res := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, val)
+	if len(res) > maxLen {
+		res = res[:maxLen]
+	}
+	if res == "" {
+		return "-"
+	}
+	return res
+}
+
+// syslogWriter writes the messages into the connection, reconnecting if the connection breaks
+type syslogWriter struct {
+	mtx     sync.Mutex
+	network string
+	addr    string
+	framed  bool
+	conn    io.Writer
+}
+
+func (w *syslogWriter) connect() error {
+	conn, err := net.DialTimeout(w.network, w.addr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *syslogWriter) write(msg []byte) error {
+	if w.framed {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+	_, err := w.conn.Write(msg)
+	if err == nil || w.network == "" {
+		return err
+	}
+
+	// Try to reconnect once
+	_ = w.closeConn()
+	if err = w.connect(); err != nil {
+		return err
+	}
+	_, err = w.conn.Write(msg)
+	return err
+}
+
+func (w *syslogWriter) closeConn() error {
+	closer, ok := w.conn.(io.Closer)
+	w.conn = nil
+	if ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (w *syslogWriter) close() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	return w.closeConn()
+}
@@ -0,0 +1,139 @@
+package tidbits
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var syslogTimeRe = regexp.MustCompile(`^(<\d+>1) \S+ `)
+
+func TestSyslogFormat(t *testing.T) {
+	t.Parallel()
+
+	data := &bytes.Buffer{}
+	logger := slog.New(NewSyslogHandler(data, true, &SyslogOptions{
+		Level: slog.LevelDebug, Facility: SyslogFacilityLocal0, AppName: "app", Hostname: "host", MsgID: "ID1",
+	}))
+
+	logger.Debug("no attrs")
+	logger.With(slog.String("component", "db")).WithGroup("req").Error("failed",
+		slog.String("quoted", `a "b" \c [d]`), slog.Group("query", slog.Int("rows", 3)))
+
+	pid := strconv.Itoa(os.Getpid())
+	rd := bufio.NewReader(data)
+	assert.Equal(t, `<135>1 host app `+pid+` ID1 - no attrs`, readFramed(t, rd))
+	assert.Equal(t, `<131>1 host app `+pid+` ID1 [slog@32473 component="db" `+
+		`req.quoted="a \"b\" \\c [d\]" req.query.rows="3"] failed`, readFramed(t, rd))
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 7, SyslogSeverity(slog.LevelDebug-4))
+	assert.Equal(t, 7, SyslogSeverity(slog.LevelDebug))
+	assert.Equal(t, 6, SyslogSeverity(slog.LevelInfo))
+	assert.Equal(t, 4, SyslogSeverity(slog.LevelWarn))
+	assert.Equal(t, 3, SyslogSeverity(slog.LevelError))
+	assert.Equal(t, 2, SyslogSeverity(slog.LevelError+4))
+}
+
+func TestSyslogUDP(t *testing.T) {
+	t.Parallel()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = pc.Close() }()
+
+	h, err := DialSyslog("udp", pc.LocalAddr().String(), &SyslogOptions{AppName: "app", Hostname: "host"})
+	assert.NoError(t, err)
+	defer func() { _ = h.Close() }()
+
+	slog.New(h).Warn("over udp", slog.Int("n", 1))
+
+	buf := make([]byte, 4096)
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(buf[:n]), `[slog@32473 n="1"] over udp`))
+	assert.True(t, strings.HasPrefix(string(buf[:n]), "<12>1 "))
+}
+
+func TestSyslogTCP(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		rd := bufio.NewReader(conn)
+		received <- []string{readFramed(t, rd), readFramed(t, rd)}
+	}()
+
+	h, err := DialSyslog("tcp", ln.Addr().String(), &SyslogOptions{AppName: "app", Hostname: "host"})
+	assert.NoError(t, err)
+	defer func() { _ = h.Close() }()
+
+	logger := slog.New(h)
+	logger.Info("first\nmultiline")
+	logger.Info("second")
+
+	msgs := <-received
+	pid := strconv.Itoa(os.Getpid())
+	assert.Equal(t, []string{`<14>1 host app ` + pid + ` - - first` + "\nmultiline",
+		`<14>1 host app ` + pid + ` - - second`}, msgs)
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "log.sock")
+	pc, err := net.ListenPacket("unixgram", path)
+	assert.NoError(t, err)
+	defer func() { _ = pc.Close() }()
+
+	h, err := DialSyslog("unixgram", path, &SyslogOptions{AppName: "app", Hostname: "host"})
+	assert.NoError(t, err)
+	defer func() { _ = h.Close() }()
+
+	slog.New(h).Error("over unix")
+
+	buf := make([]byte, 4096)
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(buf[:n]), "<11>1 "))
+	assert.True(t, strings.HasSuffix(string(buf[:n]), " - over unix"))
+}
+
+// Read the octet-counted message and strip its timestamp
+func readFramed(t *testing.T, rd *bufio.Reader) string {
+	lenStr, err := rd.ReadString(' ')
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(lenStr, " "))
+	assert.NoError(t, err)
+	msg := make([]byte, n)
+	_, err = io.ReadFull(rd, msg)
+	assert.NoError(t, err)
+	return syslogTimeRe.ReplaceAllString(string(msg), "$1 ")
+}
@@ -0,0 +1,140 @@
+package tidbitstest
+
+import (
+	"context"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type TestLoggerOptions struct {
+	Level    slog.Level
+	Colorize bool
+	// FailOnError fails the test on any record with the ERROR level (or above) that
+	// was not expected with TestLogger.ExpectError
+	FailOnError bool
+}
+
+// TestLogger is a logger that routes the output to testing.T, so it's interleaved with the
+// output of the test that produced it. The messages are formatted by PrettySink. The logger can
+// be safely used from goroutines that outlive the test, the output is dropped after the test
+// cleanup.
+type TestLogger struct {
+	*slog.Logger
+
+	mtx            sync.Mutex
+	t              testing.TB
+	done           bool
+	expectedErrors []*tidbits.RecordMatcher
+}
+
+func NewTestLogger(t testing.TB, opts TestLoggerOptions) *TestLogger {
+	res := &TestLogger{t: t}
+	t.Cleanup(func() {
+		res.mtx.Lock()
+		defer res.mtx.Unlock()
+		res.done = true
+	})
+
+	pretty := tidbits.NewPrettySink(testLogWriter{res}, opts.Level, opts.Colorize)
+	res.Logger = slog.New(&testLogHandler{
+		failOnError: opts.FailOnError,
+		delegate:    pretty.GetHandler(),
+		recorder:    tidbits.NewCapturingHandler(opts.Level, res.checkError),
+	})
+	return res
+}
+
+// ExpectError marks the ERROR records that match the matcher as expected, so they don't
+// fail the test
+func (l *TestLogger) ExpectError(m *tidbits.RecordMatcher) *TestLogger {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.expectedErrors = append(l.expectedErrors, m)
+	return l
+}
+
+func (l *TestLogger) checkError(rec tidbits.CapturedRecord) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.done {
+		return
+	}
+	for _, m := range l.expectedErrors {
+		if m.Matches(&rec) {
+			return
+		}
+	}
+	l.t.Errorf("Unexpected error logged: %s", rec.String())
+}
+
+// testOutput is implemented by testing.T since Go 1.25, the output is not prefixed with the
+// location of the logging code
+type testOutput interface {
+	Output() io.Writer
+}
+
+// Write the message to the test output. The message already has the source location of the
+// record, so the test output location is not needed.
+func (l *TestLogger) log(data []byte) {
+	l.t.Helper()
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.done {
+		return
+	}
+	if out, ok := l.t.(testOutput); ok {
+		_, _ = out.Output().Write(data)
+		return
+	}
+	l.t.Log(strings.TrimSuffix(string(data), "\n"))
+}
+
+type testLogWriter struct {
+	logger *TestLogger
+}
+
+func (w testLogWriter) Write(p []byte) (n int, err error) {
+	w.logger.t.Helper()
+	w.logger.log(p)
+	return len(p), nil
+}
+
+type testLogHandler struct {
+	failOnError bool
+	delegate    slog.Handler
+	// Captures the records for matching with the expected errors
+	recorder slog.Handler
+}
+
+var _ slog.Handler = &testLogHandler{}
+
+func (h *testLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.delegate.Enabled(ctx, level)
+}
+
+func (h *testLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.failOnError && record.Level >= slog.LevelError {
+		_ = h.recorder.Handle(ctx, record.Clone())
+	}
+	return h.delegate.Handle(ctx, record)
+}
+
+func (h *testLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &testLogHandler{
+		failOnError: h.failOnError,
+		delegate:    h.delegate.WithAttrs(attrs),
+		recorder:    h.recorder.WithAttrs(attrs),
+	}
+}
+
+func (h *testLogHandler) WithGroup(name string) slog.Handler {
+	return &testLogHandler{
+		failOnError: h.failOnError,
+		delegate:    h.delegate.WithGroup(name),
+		recorder:    h.recorder.WithGroup(name),
+	}
+}
@@ -0,0 +1,36 @@
+package tidbitstest
+
+import (
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+func TestTestLogger(t *testing.T) {
+	t.Parallel()
+
+	ft := &fakeTB{}
+	tl := NewTestLogger(ft, TestLoggerOptions{Level: slog.LevelInfo, FailOnError: true})
+	tl.ExpectError(tidbits.Expect().Msg("expected failure"))
+
+	tl.Debug("skipped")
+	tl.With(slog.String("component", "db")).Info("hello", slog.Int("key", 42))
+	tl.Error("expected failure")
+	tl.Error("unexpected failure", slog.String("reason", "boom"))
+
+	assert.Equal(t, 3, len(ft.logs))
+	assert.Equal(t, `INFO  test_logger_test.go:18  hello  component=db  key=42`, removeTimes(ft.logs[0]))
+	assert.Equal(t, []string{`Unexpected error logged: ERROR "unexpected failure" reason=boom`}, ft.errors)
+
+	// The output after the test completion is dropped
+	ft.runCleanups()
+	tl.Error("late failure")
+	assert.Equal(t, 3, len(ft.logs))
+	assert.Equal(t, 1, len(ft.errors))
+}
+
+func TestTestLoggerOutput(t *testing.T) {
+	tl := NewTestLogger(t, TestLoggerOptions{Level: slog.LevelDebug})
+	tl.Debug("this message goes to the test output", slog.Int("key", 42))
+}
@@ -0,0 +1,62 @@
+// Package tidbitstest contains the tidbits helpers that depend on the testing package, it's
+// separate from tidbits, so the production binaries don't import the testing package.
+package tidbitstest
+
+import (
+	"context"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"strings"
+	"testing"
+)
+
+// AssertLogged checks that at least one record matches. On failure, it prints all the logged
+// records along with the conditions they failed.
+func AssertLogged(t testing.TB, r *tidbits.RecordingLogger, m *tidbits.RecordMatcher) bool {
+	t.Helper()
+	records := r.Records()
+	for _, rec := range records {
+		if m.Matches(&rec) {
+			return true
+		}
+	}
+
+	var res strings.Builder
+	res.WriteString("Expected a record matching " + m.String() + ", got:")
+	if len(records) == 0 {
+		res.WriteString(" no records")
+	}
+	for _, rec := range records {
+		res.WriteString("\n\t" + rec.String())
+		res.WriteString("\n\t\tmismatched: " + strings.Join(m.Mismatches(&rec), ", "))
+	}
+	t.Error(res.String())
+	return false
+}
+
+// AssertNotLogged checks that no records match, on failure it prints the matching records
+func AssertNotLogged(t testing.TB, r *tidbits.RecordingLogger, m *tidbits.RecordMatcher) bool {
+	t.Helper()
+	found := r.Find(m)
+	if len(found) == 0 {
+		return true
+	}
+
+	var res strings.Builder
+	res.WriteString("Expected no records matching " + m.String() + ", got:")
+	for _, rec := range found {
+		res.WriteString("\n\t" + rec.String())
+	}
+	t.Error(res.String())
+	return false
+}
+
+// SinkPartition returns the context that routes the messages into the SinkingLogger partition
+// named after the test
+func SinkPartition(t testing.TB) context.Context {
+	return tidbits.WithSinkPartition(context.Background(), t.Name())
+}
+
+// GetSinkPartition returns the accumulated log data for the test partition and resets it
+func GetSinkPartition(t testing.TB, s *tidbits.SinkingLogger) string {
+	return s.GetPartition(t.Name())
+}
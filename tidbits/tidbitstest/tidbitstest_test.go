@@ -0,0 +1,121 @@
+package tidbitstest
+
+import (
+	"fmt"
+	"github.com/Cyberax/slog-tidbits/tidbits"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTB captures the test output and errors
+type fakeTB struct {
+	testing.TB
+	mtx      sync.Mutex
+	errors   []string
+	logs     []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Error(args ...any) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.errors = append(f.errors, fmt.Sprint(args...))
+}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.Error(fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Log(args ...any) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+func (f *fakeTB) Output() io.Writer {
+	return fakeOutput{f}
+}
+
+type fakeOutput struct {
+	f *fakeTB
+}
+
+func (o fakeOutput) Write(p []byte) (int, error) {
+	o.f.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeTB) runCleanups() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+// Remove the timestamps from the PrettySink output
+func removeTimes(logs string) string {
+	res := ""
+	for _, ln := range strings.Split(logs, "\n") {
+		parts := strings.SplitN(ln, "  ", 2)
+		if len(parts) == 2 {
+			res += parts[1] + "\n"
+		} else {
+			res += ln + "\n"
+		}
+	}
+	return strings.TrimSpace(res)
+}
+
+func TestAssertLogged(t *testing.T) {
+	t.Parallel()
+
+	rl := tidbits.NewRecordingLogger(slog.LevelInfo)
+	rl.Info("hello", slog.Int("count", 42))
+	sub := rl.With(slog.String("component", "db")).WithGroup("req")
+	sub.Warn("slow query", slog.Group("query", slog.String("table", "users")))
+	sub.Error("failed", slog.String("val", "WARN"))
+
+	assert.True(t, AssertLogged(t, rl, tidbits.Expect().Msg("hello").ExpectAttr("count", 42)))
+	assert.True(t, AssertNotLogged(t, rl, tidbits.Expect().MinLevel(slog.LevelError).MsgContains("slow")))
+
+	ft := &fakeTB{}
+	assert.False(t, AssertLogged(ft, rl, tidbits.Expect().Msg("hello").ExpectAttr("count", 43)))
+	assert.Equal(t, []string{`Expected a record matching {msg="hello", count=43}, got:
+	INFO "hello" count=42
+		mismatched: count=43
+	WARN "slow query" component=db req.query.table=users
+		mismatched: msg="hello", count=43
+	ERROR "failed" component=db req.val=WARN
+		mismatched: msg="hello", count=43`}, ft.errors)
+
+	ft = &fakeTB{}
+	assert.False(t, AssertNotLogged(ft, rl, tidbits.Expect().MinLevel(slog.LevelWarn)))
+	assert.Equal(t, []string{`Expected no records matching {level>=WARN}, got:
+	WARN "slow query" component=db req.query.table=users
+	ERROR "failed" component=db req.val=WARN`}, ft.errors)
+
+	rl.Reset()
+	ft = &fakeTB{}
+	assert.False(t, AssertLogged(ft, rl, tidbits.Expect()))
+	assert.Equal(t, []string{`Expected a record matching {}, got: no records`}, ft.errors)
+}
+
+func TestSinkPartition(t *testing.T) {
+	t.Parallel()
+
+	sink := tidbits.NewSinkingLogger(slog.LevelInfo)
+	sink.InfoContext(SinkPartition(t), "test message")
+	sink.Info("shared message")
+
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"test message"}`, GetSinkPartition(t, sink))
+	assert.Equal(t, `{"time":"","level":"INFO","msg":"shared message"}`, sink.Get())
+}